@@ -0,0 +1,61 @@
+//Copyright © 2019 Adedayo Adetoye (aka Dayo)
+//All rights reserved.
+//
+//Redistribution and use in source and binary forms, with or without
+//modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+//THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+//AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+//IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+//ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+//LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+//CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+//SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+//INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+//CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+//ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+//POSSIBILITY OF SUCH DAMAGE.
+package cmd
+
+import (
+	"fmt"
+
+	stats "github.com/adedayo/tls-stats/pkg"
+	"github.com/spf13/cobra"
+)
+
+//clearCmd represents the clear command
+var clearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the downloaded data",
+	Long: `Removes the downloaded browser and device data and, optionally, the generated statistics.
+Only the files tls-stats manages are removed`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := stats.ClearData(); err != nil {
+			fmt.Println(err)
+		}
+		if cmd.Flag("stats").Changed {
+			keepCurrent := cmd.Flag("keep-current").Changed
+			if err := stats.ClearStats(keepCurrent); err != nil {
+				fmt.Println(err)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clearCmd)
+	clearCmd.Flags().BoolP("stats", "s", false, "Also remove the backups of the generated statistics, and the current ones")
+	clearCmd.Flags().BoolP("keep-current", "k", false, "With --stats, keep the current statistics")
+}
@@ -50,7 +50,9 @@ Sources are:
 `, stats.BrowserStats, stats.DeviceDetails),
 	Run: func(cmd *cobra.Command, args []string) {
 		force := cmd.Flag("force").Changed
-		stats.DownloadData(force)
+		if err := stats.DownloadData(force); err != nil {
+			fmt.Println(err)
+		}
 	},
 }
 
@@ -30,6 +30,9 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+
 	stats "github.com/adedayo/tls-stats/pkg"
 	"github.com/spf13/cobra"
 )
@@ -41,12 +44,34 @@ var generateCmd = &cobra.Command{
 	Long:  `Generates the cipher usage statistics using Wikipedia visitor data`,
 	Run: func(cmd *cobra.Command, args []string) {
 		force := cmd.Flag("force").Changed
-		stats.PrintStats(force)
+		category, _ := cmd.Flags().GetString("category")
+		if category == "" {
+			stats.PrintStats(force)
+			return
+		}
+		printers := map[string]func(stats.TLSStatistics) error{
+			"protocol": func(s stats.TLSStatistics) error { return s.PrintProtocols(os.Stdout) },
+			"cipher":   func(s stats.TLSStatistics) error { return s.PrintCiphers(os.Stdout) },
+			"curve":    func(s stats.TLSStatistics) error { return s.PrintCurves(os.Stdout) },
+		}
+		printer, present := printers[category]
+		if !present {
+			fmt.Printf("unknown category %q, expected protocol, cipher or curve\n", category)
+			return
+		}
+		statistics, err := stats.GetStats(force)
+		if err == nil {
+			err = printer(statistics)
+		}
+		if err != nil {
+			fmt.Println(err)
+		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(generateCmd)
 	generateCmd.Flags().BoolP("force", "f", false, "Force re-download, and re-compute even if a recent analysis result exists")
+	generateCmd.Flags().StringP("category", "c", "", "Only print one category: protocol, cipher or curve")
 
 }
@@ -1,9 +1,10 @@
 module github.com/adedayo/tls-stats
 
-go 1.12
+go 1.14
 
 require (
 	github.com/mitchellh/go-homedir v1.1.0
+	github.com/prometheus/client_golang v0.9.2
 	github.com/spf13/cobra v0.0.3
 	github.com/spf13/viper v1.3.2
 )
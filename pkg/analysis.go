@@ -2,65 +2,252 @@ package stats
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"log"
+	"math"
 	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 //GetStats generates cipher/protocol usage statistics using Wikipedia visitor data
-func GetStats(forceDownload bool) (statistics TLSStatistics, e error) {
-	force := forceDownload
-	if force {
-		if e = DownloadData(true); e == nil {
-			renameCurrentStats()
-			return analyseAndWriteToFile()
+func GetStats(forceDownload bool) (TLSStatistics, error) {
+	if forceDownload {
+		return GetStatsWith(WithForceDownload())
+	}
+	return GetStatsWith()
+}
+
+//statsMutex serialises the generation and rotation of the statistics file, and guards the cache
+var statsMutex sync.Mutex
+
+//cache holds the most recently loaded or generated statistics
+var cache struct {
+	statistics TLSStatistics
+	loaded     time.Time
+	valid      bool
+}
+
+//InvalidateCache discards the statistics cached in memory, so that the next call reads them from disk
+func InvalidateCache() {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	cache.valid = false
+}
+
+//GetStatsWith generates cipher/protocol usage statistics using Wikipedia visitor data, customised by the options.
+//Previously generated statistics are returned as long as they are not stale, unless WithForceDownload is given.
+//They are cached in memory for the config's CacheTTL.
+//
+//Only the default analysis is kept as tls-stats-current.json and cached: options that change what is analysed or how,
+//such as WithLookback, WithPlatformFilter or WithWeights, give statistics that are computed afresh, downloading the
+//data if needed, and are neither written to file nor cached. See AnalyseContext to analyse the downloaded data
+//without downloading it
+//
+//GetStatsWith and GetStats are safe for concurrent use: callers are serialised, so those arriving while statistics
+//are being generated get the freshly generated ones rather than repeating the analysis
+func GetStatsWith(opts ...Option) (TLSStatistics, error) {
+	return GetStatsContext(context.Background(), opts...)
+}
+
+//GetStatsContext is GetStatsWith, stopping the download and the analysis and returning the context's error as soon as
+//practical once the context is done. Being serialised with the other callers, it may first wait for statistics that
+//are being generated, which the context does not interrupt
+func GetStatsContext(ctx context.Context, opts ...Option) (statistics TLSStatistics, err error) {
+	config := newConfig(opts...)
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	if !config.defaultAnalysis() {
+		//other analyses are neither cached nor written, so as not to replace or back up the current statistics
+		if err = config.download(ctx, config.ForceDownload); err != nil {
+			return
 		}
+		statistics, _, err = analyseStatistics(ctx, config)
 		return
 	}
+	if !config.ForceDownload && cache.valid && now().Sub(cache.loaded) < config.CacheTTL &&
+		!cache.statistics.GenerationDate.Before(now().Add(-config.Staleness)) {
+		return cache.statistics, nil
+	}
+	if statistics, err = getStats(ctx, config); err == nil {
+		cache.statistics = statistics
+		cache.loaded = now()
+		cache.valid = true
+	}
+	return
+}
+
+func getStats(ctx context.Context, config Config) (statistics TLSStatistics, err error) {
+	if config.ForceDownload {
+		if err = config.download(ctx, true); err != nil {
+			return
+		}
+		config.backupCurrentStats()
+		return analyseAndWriteToFile(ctx, config)
+	}
 	//check whether recent stats exists
-	if _, err := os.Stat(jsonStatsOut); os.IsNotExist(err) {
+	current := currentStatsFile()
+	if _, err = os.Stat(current); os.IsNotExist(err) {
 		//no stats. download and compute
-		e = DownloadData(false)
-		return analyseAndWriteToFile()
+		if err = config.download(ctx, false); err != nil {
+			return
+		}
+		return analyseAndWriteToFile(ctx, config)
 	}
 	//stats exist
-	if data, e := ioutil.ReadFile(jsonStatsOut); e == nil {
-		if e = json.Unmarshal(data, &statistics); e == nil {
-			if statistics.GenerationDate.Before(time.Now().AddDate(0, -6, 0)) {
-				//but it's stale
-				//move the old stats
-				renameCurrentStats()
-				e = DownloadData(false)
-				return analyseAndWriteToFile()
-			}
+	if statistics, err = LoadStatistics(current); err != nil {
+		return
+	}
+	if statistics.GenerationDate.Before(now().Add(-config.Staleness)) {
+		//but it's stale
+		//move the old stats
+		config.backupCurrentStats()
+		if err = config.download(ctx, false); err != nil {
+			return
 		}
+		return analyseAndWriteToFile(ctx, config)
 	}
 	return
 }
 
-func analyseAndWriteToFile() (TLSStatistics, error) {
-	stats, start, end := analyseStats(false)
-	statistics := stats.toJSONStruct(start, end)
-	data, err := json.MarshalIndent(statistics, "", " ")
-	if err == nil {
-		err = ioutil.WriteFile(jsonStatsOut, data, 0644)
+//download downloads the data, unless offline or reading it from explicit files.
+//Unless forced, data that has already been downloaded is kept. A source that fails to download is only an error if
+//it has never been downloaded; otherwise its most recent data is used, with a warning. A download stopped by the
+//context is always an error
+func (config Config) download(ctx context.Context, force bool) error {
+	if config.Offline || !config.DataDate.IsZero() || (config.BrowserFile != "" && (config.DeviceFile != "" || config.DeviceSource != nil)) {
+		return nil
+	}
+	for _, source := range dataSources() {
+		err := source.download(ctx, force)
+		if err == nil || (!force && errors.Is(err, ErrFileExists)) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		//carry on with the last data of the source, if any, as the other may have been refreshed
+		if file := freshestDataFile(source.file, source.dated); fileExists(file) {
+			logf("%v; using the stale data in %s", err, file)
+			continue
+		}
+		return err
+	}
+	if config.RollingData && config.BrowserFile == "" {
+		return config.updateRollingData()
+	}
+	return nil
+}
+
+//LoadStatistics reads previously generated statistics, such as tls-stats-current.json or one of its backups, from file.
+//The file may be gzip-compressed
+func LoadStatistics(path string) (TLSStatistics, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return TLSStatistics{}, fmt.Errorf("loading statistics from %s: %w", path, err)
+	}
+	defer f.Close()
+	statistics, err := LoadStatisticsReader(f)
+	if err != nil {
+		return statistics, fmt.Errorf("loading statistics from %s: %w", path, err)
+	}
+	return statistics, nil
+}
+
+//LoadStatisticsReader reads previously generated statistics in JSON form from r, decompressing them if they are gzipped
+func LoadStatisticsReader(r io.Reader) (statistics TLSStatistics, err error) {
+	if r, err = decompressed(r); err != nil {
+		return statistics, fmt.Errorf("malformed statistics: %w", err)
+	}
+	if err = json.NewDecoder(r).Decode(&statistics); err != nil {
+		err = fmt.Errorf("malformed statistics: %w", err)
+	}
+	return
+}
+
+//decompressed reads r through gzip if it is gzip-compressed, and as is otherwise
+func decompressed(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, e := br.Peek(2); e == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(br)
+	}
+	return br, nil
+}
+
+func analyseAndWriteToFile(ctx context.Context, config Config) (TLSStatistics, error) {
+	statistics, stats, err := analyseStatistics(ctx, config)
+	if err != nil {
+		return statistics, err
+	}
+	out, other := jsonStatsOut, jsonStatsOut+compressedSuffix
+	if config.Compress {
+		out, other = other, out
+		err = writeFile(out, statistics.WriteJSONGzip)
+	} else {
+		err = writeFile(out, statistics.WriteJSON)
+	}
+	if err != nil {
+		return statistics, err
+	}
+	//the current stats are in one form only
+	os.Remove(other)
+	if config.WriteRaw {
+		err = writeFile(jsonRawOut, stats.WriteJSON)
 	}
 	return statistics, err
 }
 
+//writeFile writes a file with write. It writes to a temporary file in the same directory first so that readers never
+//see a partially written file, and nothing is left if writing fails
+func writeFile(file string, write func(io.Writer) error) error {
+	f, err := ioutil.TempFile(path.Dir(file), "tls-stats-*.tmp")
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err = os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), file)
+}
+
+//backupCurrentStats renames the current stats to a dated backup, unless the config regenerates them in place
+func (config Config) backupCurrentStats() {
+	if config.NoBackup {
+		return
+	}
+	renameCurrentStats()
+}
+
 func renameCurrentStats() {
-	if _, err := os.Stat(jsonStatsOut); !os.IsNotExist(err) {
-		stats := TLSStatistics{}
-		if data, err := ioutil.ReadFile(jsonStatsOut); err == nil && json.Unmarshal(data, &stats) == nil {
+	current := currentStatsFile()
+	if _, err := os.Stat(current); !os.IsNotExist(err) {
+		if stats, err := LoadStatistics(current); err == nil {
 			jsonStatsOutBackup := path.Join(statsHome, fmt.Sprintf("tls-stats-%s.json", stats.GenerationDate.Format(dateFormat)))
-			if err := os.Rename(jsonStatsOut, jsonStatsOutBackup); err != nil {
-				log.Println(err.Error())
+			if strings.HasSuffix(current, compressedSuffix) {
+				jsonStatsOutBackup += compressedSuffix
+			}
+			if err := os.Rename(current, jsonStatsOutBackup); err != nil {
+				logf("Could not back up the current stats: %v", err)
 			}
 		}
 	}
@@ -68,87 +255,549 @@ func renameCurrentStats() {
 
 //PrintStats prints cipher/protocol usage statistics using Wikipedia visitor data
 func PrintStats(forceDownload bool) {
-	DownloadData(forceDownload)
-	stats, _, _ := analyseStats(false)
-	fmt.Printf("Stats \n%s\n", stats)
-	GetStats(false) //side effect, write JSON output
+	FprintStats(os.Stdout, forceDownload)
+}
 
+//FprintStats is PrintStats writing to w
+func FprintStats(w io.Writer, forceDownload bool) {
+	statistics, err := GetStats(forceDownload)
+	if err != nil {
+		fmt.Fprintln(w, err)
+		return
+	}
+	fmt.Fprintln(w, "Stats ")
+	statistics.Print(w)
 }
 
-func analyseStats(forceDownload bool) (TLSStats, time.Time, time.Time) {
+//AnalyseStats computes cipher/protocol usage statistics from the already downloaded data, using the given config.
+//It neither downloads data nor writes the results to file
+func AnalyseStats(config Config) (TLSStatistics, error) {
+	statistics, _, err := analyseStatistics(context.Background(), config)
+	return statistics, err
+}
 
-	browsers := loadBrowserOSStats(browserStatsData)
-	devices := loadDeviceDetails(deviceCiphers)
+//AnalyseContext is AnalyseStats with the config customised by the options. It stops and returns the context's error
+//as soon as practical once the context is done
+func AnalyseContext(ctx context.Context, opts ...Option) (TLSStatistics, error) {
+	statistics, _, err := analyseStatistics(ctx, newConfig(opts...))
+	return statistics, err
+}
 
-	found := 0
-	notfound := 0
-	m := make(map[string]int)
+//analyseStatistics is AnalyseStats, also returning the raw counts
+func analyseStatistics(ctx context.Context, config Config) (TLSStatistics, TLSStats, error) {
+	stats, start, end, err := analyseStats(ctx, config)
+	if err != nil {
+		return TLSStatistics{}, stats, err
+	}
+	statistics := stats.toJSONStruct(start, end)
+	config.setGenerationDate(&statistics)
+	//with a fixed generation date, the fetch dates are left zero so that copies of the same data give the same statistics
+	if config.GenerationDate.IsZero() {
+		statistics.BrowserDataFetched = modTime(config.browserFile())
+		if config.DeviceSource == nil {
+			statistics.DeviceDataFetched = modTime(config.deviceFile())
+		}
+	}
+	if err = config.validate(statistics); err != nil {
+		return TLSStatistics{}, stats, err
+	}
+	return statistics, stats, nil
+}
+
+//setGenerationDate overrides the generation date of the statistics with the config's, if set
+func (config Config) setGenerationDate(statistics *TLSStatistics) {
+	if !config.GenerationDate.IsZero() {
+		statistics.GenerationDate = config.GenerationDate
+	}
+}
+
+//modTime is the modification time of the file, or zero if it cannot be read
+func modTime(file string) (t time.Time) {
+	if info, err := os.Stat(file); err == nil {
+		t = info.ModTime().UTC()
+	}
+	return
+}
+
+//AnalyseFromReaders computes cipher/protocol usage statistics from browser data in the TSV form of the Wikipedia report
+//and device profiles in the JSON form of the SSL Labs API, without touching the filesystem or network
+func AnalyseFromReaders(browserTSV io.Reader, deviceJSON io.Reader, opts ...Option) (TLSStatistics, error) {
+	config := newConfig(opts...)
+	browsers, _, err := loadBrowserStats(browserTSV, config)
+	if err != nil {
+		return TLSStatistics{}, err
+	}
+	devices, _, err := LoadDeviceDetailsReader(deviceJSON)
+	if err != nil {
+		return TLSStatistics{}, err
+	}
+	stats, start, end, err := analyse(context.Background(), browsers, devices, config)
+	if err != nil {
+		return TLSStatistics{}, err
+	}
+	statistics := stats.toJSONStruct(start, end)
+	config.setGenerationDate(&statistics)
+	if err = config.validate(statistics); err != nil {
+		return TLSStatistics{}, err
+	}
+	return statistics, nil
+}
+
+func analyseStats(ctx context.Context, config Config) (TLSStats, time.Time, time.Time, error) {
+	if err := config.checkDataDate(); err != nil {
+		return TLSStats{}, time.Time{}, time.Time{}, err
+	}
+	browsers, err := config.loadBrowserOSStats(ctx, config.browserFile())
+	if err != nil {
+		return TLSStats{}, time.Time{}, time.Time{}, err
+	}
+	devices, err := config.deviceSource().Load()
+	if err != nil {
+		return TLSStats{}, time.Time{}, time.Time{}, err
+	}
+	return analyse(ctx, browsers, devices, config)
+}
+
+//checkDataDate reports the available dates if the data for the config's DataDate is missing
+func (config Config) checkDataDate() error {
+	if config.DataDate.IsZero() {
+		return nil
+	}
+	_, browserErr := os.Stat(config.browserFile())
+	_, deviceErr := os.Stat(config.deviceFile())
+	if browserErr == nil && (deviceErr == nil || config.DeviceSource != nil) {
+		return nil
+	}
+	available := []string{}
+	dates, _ := DataDates()
+	for _, d := range dates {
+		available = append(available, d.Format(dateFormat))
+	}
+	return fmt.Errorf("%w for %s; data is available for: %s", ErrNoData, config.DataDate.Format(dateFormat), strings.Join(available, ", "))
+}
+
+//analyse joins the browsers to the device profiles and aggregates the TLS support of the matched browsers.
+//It fails if there are no browsers, or none of them match a device profile, and stops if the context is done
+func analyse(ctx context.Context, browsers []Browser, devices []Device, config Config) (TLSStats, time.Time, time.Time, error) {
+	start, end, err := getDateRange(browsers)
+	if err != nil {
+		return TLSStats{}, start, end, err
+	}
+	if err := config.checkDataDays(browsers); err != nil {
+		return TLSStats{}, start, end, err
+	}
+	devices = config.filterDevices(devices)
+
+	quality := QualityReport{Records: len(browsers)}
+	browserKey := config.browserKeyer()
 	browserMap := make(map[string]int64)
-	deviceKeys := make(map[string]bool)
+	deviceKeys := indexDevices(devices, config.KeyStrategy)
+	familyCounts := make(map[string]int64)
+	unmatchedFamilies := make(map[string]int64)
+	for i, b := range browsers {
+		if i%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return TLSStats{}, start, end, err
+			}
+		}
+		if !config.platformIncluded(b) {
+			quality.Excluded++
+			continue
+		}
+		key := browserKey(b)
+		if config.excluded(key) {
+			quality.Excluded++
+			continue
+		}
+		count := config.weigh(key, b.Count)
+		family := key
+		if i := strings.IndexByte(key, ':'); i >= 0 {
+			family = key[:i]
+		}
+		familyCounts[family] += count
+		if family == otherFamily {
+			quality.Other += count
+		}
+		if matched, present := config.matchDevice(deviceKeys, key, b); present {
+			quality.Matched++
+			quality.MatchedCount += count
+			browserMap[matched] += count
+		} else {
+			quality.Unmatched++
+			quality.UnmatchedCount += count
+			unmatchedFamilies[family] += count
+		}
+
+	}
+	if quality.Matched == 0 {
+		return TLSStats{}, start, end, ErrNoMatchingBrowsers
+	}
+	families := []string{}
+	for family := range unmatchedFamilies {
+		families = append(families, family)
+	}
+	sort.Strings(families)
+	for _, family := range families {
+		count := unmatchedFamilies[family]
+		if !config.hasVersionCollapse(family) {
+			continue
+		}
+		if quality.Uncollapsed == nil {
+			quality.Uncollapsed = make(map[string]int64)
+		}
+		quality.Uncollapsed[family] = count
+		if share := float64(count) / float64(familyCounts[family]); share > uncollapsedWarning {
+			logf("%.0f%% of %s traffic matched no device profile; the version collapse tables may need updating", 100*share, family)
+		}
+	}
+	stats := getTLSStats(browserMap, devices, deviceKeys, config.ProtocolFloor, config.workers())
+	if config.Denominator == ObservedTraffic {
+		stats.Total += quality.UnmatchedCount
+	}
+	stats.quality = quality
+	return stats, start, end, nil
+}
+
+//QualityReport describes how well the browser records joined to the device profiles. The statistics only reflect
+//the matched browsers, so a low MatchRate means they should not be trusted
+type QualityReport struct {
+	Records        int   `json:"records"`         //browser records analysed
+	Matched        int   `json:"matched"`         //records matching a device profile
+	Unmatched      int   `json:"unmatched"`       //records matching no device profile
+	Excluded       int   `json:"excluded"`        //records dropped by the exclusions or the platform filter
+	MatchedCount   int64 `json:"matched_count"`   //weighted number of clients of the matched records
+	UnmatchedCount int64 `json:"unmatched_count"` //weighted number of clients of the unmatched records
+
+	//Other is the weighted number of clients of the "Other" browser family of the Wikipedia data, which matches no
+	//device profile unless proxied, so the match rate cannot exceed the share of the rest. See Config.Proxies and
+	//Config.Exclusions to count or drop them
+	Other int64 `json:"other"`
+
+	//Uncollapsed is the weighted number of unmatched clients of each browser family with a version collapse table.
+	//These are typically new versions missing from the tables, see RegisterVersionCollapse
+	Uncollapsed map[string]int64 `json:"uncollapsed,omitempty"`
+}
+
+//UnmatchedDevices returns the devices, e.g. from a DeviceSource, that matched no browser traffic in the statistics,
+//according to their DeviceCounts. These are rare clients or suggest a mismatch between browser and device keys
+func (t TLSStatistics) UnmatchedDevices(devices []Device) []Device {
+	unmatched := []Device{}
 	for _, d := range devices {
-		deviceKeys[deviceKey(d)] = true
+		matched := false
+		for _, strategy := range []KeyStrategy{BrowserOnlyKey, BrowserOSKey, BrowserOSVersionKey} {
+			matched = matched || t.DeviceCounts[strategy.deviceIndexKey(d)] > 0
+		}
+		if !matched {
+			unmatched = append(unmatched, d)
+		}
+	}
+	return unmatched
+}
+
+//otherFamily is the browser family of the Wikipedia data that buckets the uncommon browsers
+const otherFamily = "Other"
+
+//uncollapsedWarning is the share of a browser family's clients that, if unmatched, is logged as a warning
+const uncollapsedWarning = 0.05
+
+//MatchRate is the fraction of the weighted clients that matched a device profile
+func (q QualityReport) MatchRate() float64 {
+	if total := q.MatchedCount + q.UnmatchedCount; total > 0 {
+		return float64(q.MatchedCount) / float64(total)
+	}
+	return 0
+}
+
+func (q QualityReport) String() string {
+	out := fmt.Sprintf("%d of %d browser records matched a device profile, covering %.2f%% of clients",
+		q.Matched, q.Records, 100*q.MatchRate())
+	if total := q.MatchedCount + q.UnmatchedCount; q.Other > 0 && total > 0 {
+		out += fmt.Sprintf("; %.2f%% of clients use Other browsers", 100*float64(q.Other)/float64(total))
+	}
+	return out
+}
+
+//GetStatsByOS generates cipher/protocol usage statistics for each OS family using Wikipedia visitor data.
+//The percentages are relative to the visitors using each OS family
+func GetStatsByOS(forceDownload bool) (map[string]TLSStatistics, error) {
+	config := DefaultConfig()
+	if err := config.download(context.Background(), forceDownload); err != nil {
+		return nil, err
+	}
+	stats, start, end, err := analyseStatsByOS(config)
+	if err != nil {
+		return nil, err
+	}
+	statistics := make(map[string]TLSStatistics)
+	for family, s := range stats {
+		statistics[family] = s.toJSONStruct(start, end)
+	}
+	return statistics, nil
+}
+
+func analyseStatsByOS(config Config) (map[string]TLSStats, time.Time, time.Time, error) {
+	if err := config.checkDataDate(); err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	browsers, err := config.loadBrowserOSStats(context.Background(), config.browserFile())
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+	devices, err := config.deviceSource().Load()
+	if err != nil {
+		return nil, time.Time{}, time.Time{}, err
 	}
+	devices = config.filterDevices(devices)
+	start, end, err := getDateRange(browsers)
+	if err != nil {
+		return nil, start, end, err
+	}
+	if err := config.checkDataDays(browsers); err != nil {
+		return nil, start, end, err
+	}
+	return getTLSStatsByOS(browsers, devices, config), start, end, nil
+}
+
+//getTLSStatsByOS computes TLS stats separately for the browsers of each OS family
+func getTLSStatsByOS(browsers []Browser, devices []Device, config Config) map[string]TLSStats {
+	deviceKeys := indexDevices(devices, config.KeyStrategy)
+	browserKey := config.browserKeyer()
+
+	browserMaps := make(map[string]map[string]int64)
+	unmatched := make(map[string]int64)
 	for _, b := range browsers {
+		if !config.platformIncluded(b) {
+			continue
+		}
 		key := browserKey(b)
-		if _, present := deviceKeys[key]; present {
-			found++
-			if count, present := browserMap[key]; present {
-				browserMap[key] = count + b.Count
-			} else {
-				browserMap[key] = b.Count
+		if config.excluded(key) {
+			continue
+		}
+		if matched, present := config.matchDevice(deviceKeys, key, b); present {
+			browserMap, present := browserMaps[b.OSFamily]
+			if !present {
+				browserMap = make(map[string]int64)
+				browserMaps[b.OSFamily] = browserMap
 			}
+			browserMap[matched] += config.weigh(key, b.Count)
 		} else {
-			notfound++
-			if count, ok := m[key]; ok {
-				m[key] = count + 1
-			} else {
-				m[key] = 1
-			}
+			unmatched[b.OSFamily] += config.weigh(key, b.Count)
 		}
+	}
 
+	stats := make(map[string]TLSStats)
+	for family, browserMap := range browserMaps {
+		familyStats := getTLSStats(browserMap, devices, deviceKeys, config.ProtocolFloor, config.workers())
+		if config.Denominator == ObservedTraffic {
+			familyStats.Total += unmatched[family]
+		}
+		stats[family] = familyStats
 	}
-	stats := getTLSStats(browserMap, devices)
-	start, end := getDateRange(browsers)
-	return stats, start, end
+	return stats
 }
 
-func getDateRange(browsers []Browser) (start, end time.Time) {
-	if len(browsers) > 0 {
-		start = browsers[0].Date
-		end = browsers[0].Date
-		for _, br := range browsers {
-			date := br.Date
-			if start.After(date) {
-				start = date
-			}
-			if end.Before(date) {
-				end = date
-			}
+//getDateRange is the span of the browser data. Without browsers there is no span, which is an error
+func getDateRange(browsers []Browser) (start, end time.Time, err error) {
+	if len(browsers) == 0 {
+		return start, end, ErrNoData
+	}
+	start = browsers[0].Date
+	end = browsers[0].Date
+	for _, br := range browsers {
+		date := br.Date
+		if start.After(date) {
+			start = date
+		}
+		if end.Before(date) {
+			end = date
 		}
 	}
 	return
 }
 
-func getTLSStats(browsers map[string]int64, devices []Device) TLSStats {
-	protocols := make(map[int]int64)
-	ciphers := make(map[int]int64)
-	curves := make(map[int]int64)
+//checkDataDays fails if the browser data has fewer distinct dates than the config's MinDataDays, as from a truncated
+//download, whose statistics would not be representative. Aggregated data has no dates and is not checked
+func (config Config) checkDataDays(browsers []Browser) error {
+	if config.MinDataDays <= 0 {
+		return nil
+	}
+	dates := make(map[time.Time]bool)
+	for _, b := range browsers {
+		if b.Date.IsZero() {
+			return nil
+		}
+		dates[b.Date] = true
+	}
+	if len(dates) < config.MinDataDays {
+		return fmt.Errorf("%w: %d, fewer than the minimum of %d", ErrTooFewDays, len(dates), config.MinDataDays)
+	}
+	return nil
+}
 
-	deviceKeys := make(map[string]Device)
+//indexDevices maps the device keys under the strategy to the devices. Devices with the same key, which SSL Labs
+//sometimes lists, are merged as by mergeDevices, so the result does not depend on their order
+func indexDevices(devices []Device, strategy KeyStrategy) map[string]Device {
+	deviceKeys := make(map[string]Device, len(devices))
 	for _, d := range devices {
-		deviceKeys[deviceKey(d)] = d
+		key := strategy.deviceIndexKey(d)
+		if existing, present := deviceKeys[key]; present {
+			d = mergeDevices(existing, d)
+		}
+		deviceKeys[key] = d
+	}
+	return deviceKeys
+}
+
+//mergeDevices combines the capabilities of two profiles of the same device: the widest protocol range and the union
+//of the cipher suites and curves, in the order the first lists them followed by those only the second lists
+func mergeDevices(a, b Device) Device {
+	merged := a
+	if normaliseProtocol(b.LowestProtocol) < normaliseProtocol(a.LowestProtocol) {
+		merged.LowestProtocol = b.LowestProtocol
+	}
+	if normaliseProtocol(b.HighestProtocol) > normaliseProtocol(a.HighestProtocol) {
+		merged.HighestProtocol = b.HighestProtocol
 	}
 
+	merged.SuiteIds = append([]int{}, a.SuiteIds...)
+	merged.SuiteNames = append([]string{}, a.SuiteNames...)
+	suites := make(map[int]bool)
+	for _, id := range a.SuiteIds {
+		suites[id] = true
+	}
+	for i, id := range b.SuiteIds {
+		if !suites[id] {
+			suites[id] = true
+			merged.SuiteIds = append(merged.SuiteIds, id)
+			if i < len(b.SuiteNames) {
+				merged.SuiteNames = append(merged.SuiteNames, b.SuiteNames[i])
+			}
+		}
+	}
+
+	merged.EllipticCurves = append([]int{}, a.EllipticCurves...)
+	curves := make(map[int]bool)
+	for _, id := range a.EllipticCurves {
+		curves[id] = true
+	}
+	for _, id := range b.EllipticCurves {
+		if !curves[id] {
+			curves[id] = true
+			merged.EllipticCurves = append(merged.EllipticCurves, id)
+		}
+	}
+	return merged
+}
+
+//getTLSStats counts the support of protocols, from protocolFloor upwards, ciphers and curves.
+//deviceKeys is the index of the devices, see indexDevices. The browsers are partitioned across the given number of
+//workers, each counting into its own maps, which are then summed
+func getTLSStats(browsers map[string]int64, devices []Device, deviceKeys map[string]Device, protocolFloor, workers int) TLSStats {
+	keys := make([]string, 0, len(browsers))
+	for b := range browsers {
+		keys = append(keys, b)
+	}
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	partials := make([]partialStats, workers)
+	size := (len(keys) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for w := range partials {
+		start, end := w*size, (w+1)*size
+		if start > len(keys) {
+			start = len(keys)
+		}
+		if end > len(keys) {
+			end = len(keys)
+		}
+		wg.Add(1)
+		go func(partial *partialStats, keys []string) {
+			defer wg.Done()
+			*partial = countTLSStats(keys, browsers, deviceKeys, protocolFloor)
+		}(&partials[w], keys[start:end])
+	}
+	wg.Wait()
+
+	stats := TLSStats{
+		Protocols:    make(map[int]int64),
+		Ciphers:      make(map[int]int64),
+		Curves:       make(map[int]int64),
+		devices:      devices,
+		Highest:      make(map[int]int64),
+		DeviceCounts: make(map[string]int64),
+	}
+	missing := []string{}
+	for _, partial := range partials {
+		stats.add(partial.stats)
+		missing = append(missing, partial.missing...)
+	}
+	//logged here rather than by the workers, as a Logger need not be safe for concurrent use
+	sort.Strings(missing)
+	for _, b := range missing {
+		logf("Could not find device with browser profile: %s", b)
+	}
+	return stats
+}
+
+//partialStats are the counts of a worker of getTLSStats and the browsers it found no device for
+type partialStats struct {
+	stats   TLSStats
+	missing []string
+}
+
+//add sums the counts of other into stats
+func (stats *TLSStats) add(other TLSStats) {
+	stats.Total += other.Total
+	stats.LegacyOnly += other.LegacyOnly
+	stats.Downgradable += other.Downgradable
+	for _, m := range [][2]map[int]int64{
+		{stats.Protocols, other.Protocols},
+		{stats.Ciphers, other.Ciphers},
+		{stats.Curves, other.Curves},
+		{stats.Highest, other.Highest},
+	} {
+		for k, v := range m[1] {
+			m[0][k] += v
+		}
+	}
+	for k, v := range other.DeviceCounts {
+		stats.DeviceCounts[k] += v
+	}
+}
+
+//countTLSStats counts the support of the given browsers, see getTLSStats
+func countTLSStats(keys []string, browsers map[string]int64, deviceKeys map[string]Device, protocolFloor int) (partial partialStats) {
+	protocols := make(map[int]int64)
+	ciphers := make(map[int]int64)
+	curves := make(map[int]int64)
+
 	total := int64(0)
-	for b, c := range browsers {
+	legacyOnly := int64(0)
+	downgradable := int64(0)
+	highest := make(map[int]int64)
+	deviceCounts := make(map[string]int64)
+	for _, b := range keys {
+		c := browsers[b]
 		total += c
 		if dev, found := deviceKeys[b]; found {
+			deviceCounts[b] += c
 			//count protocol support
-			lowestProtocol := 768 // limit protocols floor to SSL v3
-			if dev.LowestProtocol > lowestProtocol {
-				lowestProtocol = dev.LowestProtocol
+			lowestProtocol := normaliseProtocol(dev.LowestProtocol)
+			highestProtocol := normaliseProtocol(dev.HighestProtocol)
+			highest[highestProtocol] += c
+			if isWeakProtocol(highestProtocol) {
+				legacyOnly += c
+			} else if lowestProtocol <= tls.VersionTLS10 {
+				downgradable += c
 			}
-			for p := lowestProtocol; p <= dev.HighestProtocol; p++ {
+			for _, p := range protocolVersions {
+				if p < protocolFloor || p < lowestProtocol || p > highestProtocol {
+					continue
+				}
 				if count, present := protocols[p]; present {
 					protocols[p] = count + c
 				} else {
@@ -158,6 +807,9 @@ func getTLSStats(browsers map[string]int64, devices []Device) TLSStats {
 
 			//count cipher support
 			for _, cid := range dev.SuiteIds {
+				if isGREASE(cid) {
+					continue
+				}
 				if count, present := ciphers[cid]; present {
 					ciphers[cid] = count + c
 				} else {
@@ -167,6 +819,9 @@ func getTLSStats(browsers map[string]int64, devices []Device) TLSStats {
 
 			//count elliptic curve support
 			for _, cid := range dev.EllipticCurves {
+				if isGREASE(cid) {
+					continue
+				}
 				if count, present := curves[cid]; present {
 					curves[cid] = count + c
 				} else {
@@ -175,21 +830,60 @@ func getTLSStats(browsers map[string]int64, devices []Device) TLSStats {
 			}
 
 		} else {
-			fmt.Printf("Could not find device with browser profile: %s\n", b)
+			partial.missing = append(partial.missing, b)
 		}
 	}
 
-	return TLSStats{
+	partial.stats = TLSStats{
 		Protocols: protocols,
 		Ciphers:   ciphers,
 		Curves:    curves,
 		Total:     total,
-		devices:   devices,
+
+		LegacyOnly:   legacyOnly,
+		Downgradable: downgradable,
+		Highest:      highest,
+		DeviceCounts: deviceCounts,
+	}
+	return
+}
+
+//normaliseProtocol maps TLS 1.3 draft versions (0x7F followed by the draft number), which some device profiles
+//report as their highest protocol, to tls.VersionTLS13, and caps the version at TLS 1.3
+func normaliseProtocol(p int) int {
+	if p>>8 == 0x7F || p > tls.VersionTLS13 {
+		return tls.VersionTLS13
 	}
+	return p
 }
 
-func browserKey(browser Browser) string {
-	return collapseVersion(fmt.Sprintf("%s:%s", dedupFamily(browser.BrowserFamily), browser.BrowserMajorVersion))
+//browserKeyer returns a memoised browserKey. The same browser family and version recur on every day of the data,
+//so each is only collapsed once
+func (config Config) browserKeyer() func(Browser) string {
+	keys := make(map[[2]string]string)
+	return func(browser Browser) string {
+		raw := [2]string{browser.BrowserFamily, browser.BrowserMajorVersion}
+		key, present := keys[raw]
+		if !present {
+			key = config.browserKey(browser)
+			keys[raw] = key
+		}
+		return key
+	}
+}
+
+//browserKey collapses the browser family and version, preferring the config's collapse rules to the built-in ones
+func (config Config) browserKey(browser Browser) string {
+	family, present := config.FamilyCollapse[browser.BrowserFamily]
+	if !present {
+		family = dedupFamily(browser.BrowserFamily)
+	}
+	if versions, present := config.VersionCollapse[family]; present {
+		if version, present := versions[browser.BrowserMajorVersion]; present {
+			return family + ":" + version
+		}
+	}
+	return collapseVersion(family + ":" + browser.BrowserMajorVersion)
 }
 
 func dedupFamily(browser string) string {
@@ -218,8 +912,6 @@ func dedupFamily(browser string) string {
 		return "Safari"
 	case "Mobile Safari UI/WKWebView":
 		return "Safari"
-	case "Samsung Internet":
-		return "Android"
 	case "IE Mobile":
 		return "IE"
 	case "Edge Mobile":
@@ -229,110 +921,431 @@ func dedupFamily(browser string) string {
 	}
 }
 
+//versionMutex guards the version collapse tables
+var versionMutex sync.RWMutex
+
+//RegisterVersionCollapse makes the built-in version collapsing count the major version of the browser family as the
+//collapsed version, e.g. RegisterVersionCollapse("Chrome", "75", "71") when Chrome 75 ships before SSL Labs has a
+//profile for it. Samsung Internet versions collapse to the Chrome version they are based on.
+//Config.VersionCollapse takes precedence over these tables
+func RegisterVersionCollapse(family, version, collapsed string) {
+	versionMutex.Lock()
+	defer versionMutex.Unlock()
+	versions, present := versionTables[family]
+	if !present {
+		versions = make(map[string]string)
+		versionTables[family] = versions
+	}
+	versions[version] = collapsed
+}
+
+//hasVersionCollapse reports whether the browser family has a version collapse table
+func (config Config) hasVersionCollapse(family string) bool {
+	if _, present := config.VersionCollapse[family]; present {
+		return true
+	}
+	versionMutex.RLock()
+	defer versionMutex.RUnlock()
+	_, present := versionTables[family]
+	return present
+}
+
+//VersionCollapseTables returns a copy of the built-in version collapse tables, by browser family
+func VersionCollapseTables() map[string]map[string]string {
+	versionMutex.RLock()
+	defer versionMutex.RUnlock()
+	tables := make(map[string]map[string]string, len(versionTables))
+	for family, versions := range versionTables {
+		table := make(map[string]string, len(versions))
+		for v, c := range versions {
+			table[v] = c
+		}
+		tables[family] = table
+	}
+	return tables
+}
+
 func collapseVersion(browser string) string {
-	if fv := strings.Split(browser, ":"); len(fv) == 2 {
-		fam := fv[0]
-		ver := fv[1]
-		switch fam {
-		case "Chrome":
-			if nv, present := chromeVers[ver]; present {
-				return fmt.Sprintf("%s:%s", fam, nv)
-			}
-			return browser
-		case "Firefox":
-			if nv, present := firefoxVers[ver]; present {
-				return fmt.Sprintf("%s:%s", fam, nv)
-			}
-			return browser
-		case "Android":
-			if nv, present := androidVers[ver]; present {
-				return fmt.Sprintf("%s:%s", fam, nv)
-			}
-			return browser
-		case "Safari":
-			if nv, present := safariVers[ver]; present {
-				return fmt.Sprintf("%s:%s", fam, nv)
-			}
-			return browser
-		case "Opera":
-			if nv, present := operaVers[ver]; present {
-				return fmt.Sprintf("%s:%s", fam, nv)
-			}
-			return browser
-		case "Edge":
-			if nv, present := edgeVers[ver]; present {
-				return fmt.Sprintf("%s:%s", fam, nv)
-			}
-			return browser
-		case "IE":
-			if nv, present := ieVers[ver]; present {
-				return fmt.Sprintf("%s:%s", fam, nv)
-			}
-			return browser
-		default:
+	if i := strings.IndexByte(browser, ':'); i >= 0 && strings.IndexByte(browser[i+1:], ':') < 0 {
+		fam := browser[:i]
+		ver := browser[i+1:]
+		versionMutex.RLock()
+		nv, present := versionTables[fam][ver]
+		versionMutex.RUnlock()
+		if !present {
 			return browser
+		}
+		if fam == "Samsung Internet" {
+			//Samsung Internet is Chromium based, so it is matched against the Chrome profiles
+			return collapseVersion("Chrome:" + nv)
+		}
+		return fam + ":" + nv
+	}
+	return browser
+}
+
+func deviceKey(device Device) string {
+	return device.Name + ":" + device.Version
+}
+
+//cancelCheckInterval is how many browser records are joined between checks for cancellation
+const cancelCheckInterval = 4096
+
+//contextReader fails reads once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+//loadBrowserOSStats loads the browser data that falls within the config's lookback of the most recent entry.
+//If the data spans less than lookback, everything is used.
+//Reading stops if the context is done
+func (config Config) loadBrowserOSStats(ctx context.Context, file string) ([]Browser, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if file == rollingBrowserStats {
+		//the rolling data has its own format, whatever that of the downloaded data
+		config.BrowserColumns, config.DateLayout = nil, DefaultDateLayout
+	}
+	browsers, summary, err := loadBrowserStats(contextReader{ctx, f}, config)
+	if err != nil {
+		return browsers, fmt.Errorf("%s: %w", file, err)
+	}
+	if summary.Skipped() > 0 {
+		logf("Skipped rows of %s: %s", file, summary)
+	}
+	return browsers, nil
+}
+
+//ParseSummary reports how many rows of browser data were read and why any were skipped
+type ParseSummary struct {
+	Rows          int //data rows read, excluding any header
+	TooFewColumns int //rows with fewer columns than expected
+	BadDate       int //rows whose date column does not parse
+	BadCount      int //rows whose count column is not an integer
+}
+
+//Skipped is the number of malformed rows
+func (p ParseSummary) Skipped() int {
+	return p.TooFewColumns + p.BadDate + p.BadCount
+}
+
+func (p ParseSummary) String() string {
+	return fmt.Sprintf("%d of %d rows skipped (%d with too few columns, %d with a bad date, %d with a bad count)",
+		p.Skipped(), p.Rows, p.TooFewColumns, p.BadDate, p.BadCount)
+}
 
+//BrowserColumns are the positions, counting from 0, of the columns used from the browser TSV.
+//The OS columns are optional: a negative position means the report has no such column. Without a date column, the
+//data is taken to be already aggregated, see AggregatedColumns
+type BrowserColumns struct {
+	Date, OSFamily, OSMajor, BrowserFamily, BrowserMajor, Count int
+}
+
+var (
+	//DefaultBrowserColumns is the layout of the Wikipedia "by OS and browser" report, assumed when it has no header row
+	DefaultBrowserColumns = BrowserColumns{Date: 0, OSFamily: 1, OSMajor: 2, BrowserFamily: 3, BrowserMajor: 4, Count: 5}
+	//BrowserOnlyColumns is the layout of the Wikipedia "by browser" report, which has no OS columns
+	BrowserOnlyColumns = BrowserColumns{Date: 0, OSFamily: -1, OSMajor: -1, BrowserFamily: 1, BrowserMajor: 2, Count: 3}
+	//AggregatedColumns is the layout of pre-aggregated data such as a StatCounter export: one row per browser version
+	//with its share, which may be fractional, and no date. All rows are used, as there is no window to apply
+	AggregatedColumns = BrowserColumns{Date: -1, OSFamily: -1, OSMajor: -1, BrowserFamily: 0, BrowserMajor: 1, Count: 2}
+)
+
+//shareScale converts the fractional shares of aggregated data to counts, keeping the precision of shares given
+//as percentages to several decimal places
+const shareScale = 1e6
+
+//aggregated reports whether the layout has no date column, i.e. the counts are already aggregated shares
+func (c BrowserColumns) aggregated() bool {
+	return c.Date < 0
+}
+
+//parseCount parses the count column, which is an integer, or a share scaled by shareScale for aggregated data
+func (c BrowserColumns) parseCount(value string) (int64, error) {
+	if !c.aggregated() {
+		return strconv.ParseInt(value, 10, 64)
+	}
+	share, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if share < 0 {
+		return 0, fmt.Errorf("negative share %s", value)
+	}
+	return int64(math.Round(share * shareScale)), nil
+}
+
+//minColumns is the number of columns a row needs to contain all the used columns
+func (c BrowserColumns) minColumns() int {
+	last := 0
+	for _, i := range []int{c.Date, c.OSFamily, c.OSMajor, c.BrowserFamily, c.BrowserMajor, c.Count} {
+		if i > last {
+			last = i
 		}
 	}
+	return last + 1
+}
 
-	return browser
+//column is the value of the column at position i of the row, or empty if the position is negative
+func column(data []string, i int) string {
+	if i < 0 {
+		return ""
+	}
+	return data[i]
+}
 
+//parseBrowserHeader maps the columns of a header row by name. The OS columns may be missing, as may the date column
+//if there is a share column instead of a count, in which case the data is aggregated
+func parseBrowserHeader(header []string) (c BrowserColumns, err error) {
+	c.Date, c.OSFamily, c.OSMajor = -1, -1, -1
+	names := map[string]*int{
+		"date":           &c.Date,
+		"os_family":      &c.OSFamily,
+		"os_major":       &c.OSMajor,
+		"browser_family": &c.BrowserFamily,
+		"browser_major":  &c.BrowserMajor,
+		"view_count":     &c.Count,
+	}
+	found := make(map[string]bool)
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(h))
+		if name == "count" {
+			name = "view_count"
+		}
+		if name == "share" {
+			name = "view_count"
+			found["share"] = true
+		}
+		if pos, present := names[name]; present {
+			*pos = i
+			found[name] = true
+		}
+	}
+	required := []string{"date", "browser_family", "browser_major", "view_count"}
+	if found["share"] && !found["date"] {
+		required = required[1:]
+	}
+	for _, name := range required {
+		if !found[name] {
+			return c, fmt.Errorf("%w: header %q has no %s column", ErrParseBrowserData, strings.Join(header, "\t"), name)
+		}
+	}
+	return
 }
 
-func deviceKey(device Device) string {
-	return fmt.Sprintf("%s:%s", device.Name, device.Version)
-}
-
-//load about 1 year's worth of data.
-func loadBrowserOSStats(file string) (browsers []Browser) {
-	year, _, _ := time.Now().Date()
-	yearAgo := time.Date(year-1, 0, 0, 0, 0, 0, 0, time.UTC) // a year ago and a bit
-	if f, err := os.Open(file); err == nil {
-		defer f.Close()
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			line := scanner.Text()
-			data := strings.Split(line, "\t")
-			if len(data) > 4 {
-				if percent, err := strconv.ParseInt(data[5], 10, 64); err == nil {
-					if date, err := time.Parse(dateFormat, data[0]); err == nil && date.After(yearAgo) {
-						browser := Browser{
-							Date:                date,
-							BrowserFamily:       data[3],
-							BrowserMajorVersion: data[4],
-							OSFamily:            data[1],
-							OSMajorVersion:      data[2],
-							Count:               percent,
-						}
-						browsers = append(browsers, browser)
+//isBrowserHeader reports whether the first row is a header rather than data in the given layout: for dated data, if
+//its date column is not a date, and for aggregated data, if its count column is not a share
+func isBrowserHeader(row []string, columns BrowserColumns, dateLayout string) bool {
+	if columns.aggregated() {
+		if len(row) < columns.minColumns() {
+			return true
+		}
+		_, err := columns.parseCount(row[columns.Count])
+		return err != nil
+	}
+	if columns.Date >= len(row) {
+		return true
+	}
+	_, err := time.Parse(dateLayout, row[columns.Date])
+	return err != nil
+}
+
+//LoadBrowserStatsReader parses Wikipedia browser and OS data in TSV form, keeping the entries that fall within lookback
+//of the most recent entry, or all of them if lookback is not positive. Entries that are already out of the window are
+//dropped while scanning.
+//
+//If the first row is a header, columns are located by name, otherwise the fixed layout of the Wikipedia report,
+//DefaultBrowserColumns, is assumed. Malformed rows are skipped and counted in the summary, but an error is returned if
+//most rows are malformed, which suggests the upstream format has changed
+func LoadBrowserStatsReader(r io.Reader, lookback time.Duration) (browsers []Browser, summary ParseSummary, err error) {
+	return loadBrowserStats(r, Config{Lookback: lookback})
+}
+
+//LoadBrowserStatsReaderColumns is LoadBrowserStatsReader for a report with the given layout, e.g. BrowserOnlyColumns.
+//A header row, if any, is skipped
+func LoadBrowserStatsReaderColumns(r io.Reader, lookback time.Duration, columns BrowserColumns) (browsers []Browser, summary ParseSummary, err error) {
+	return loadBrowserStats(r, Config{Lookback: lookback, BrowserColumns: &columns})
+}
+
+//loadBrowserStats parses browser data with the config's BrowserColumns or, if nil, the layout of its header or the
+//default one. Dates are parsed with the config's DateLayout, or DefaultDateLayout if empty. The rows are restricted
+//to the config's date range if set, otherwise to its lookback if positive, unless the data is aggregated and has no dates
+func loadBrowserStats(r io.Reader, config Config) (browsers []Browser, summary ParseSummary, err error) {
+	dateLayout := config.DateLayout
+	if dateLayout == "" {
+		dateLayout = DefaultDateLayout
+	}
+	layout := config.BrowserColumns
+	columns := DefaultBrowserColumns
+	if layout != nil {
+		columns = *layout
+	}
+	lookback := config.Lookback
+	ranged := !config.StartDate.IsZero() || !config.EndDate.IsZero()
+	var end time.Time
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		data := strings.Split(scanner.Text(), "\t")
+		if first {
+			first = false
+			if isBrowserHeader(data, columns, dateLayout) {
+				if layout == nil {
+					if columns, err = parseBrowserHeader(data); err != nil {
+						return
 					}
 				}
+				continue
+			}
+		}
+		summary.Rows++
+		if len(data) < columns.minColumns() {
+			summary.TooFewColumns++
+			continue
+		}
+		var date time.Time
+		if !columns.aggregated() {
+			var e error
+			if date, e = time.Parse(dateLayout, data[columns.Date]); e != nil {
+				summary.BadDate++
+				continue
+			}
+		}
+		count, e := columns.parseCount(data[columns.Count])
+		if e != nil {
+			summary.BadCount++
+			continue
+		}
+		switch {
+		case columns.aggregated():
+			//already aggregated, so there is no window to apply
+		case ranged:
+			if date.Before(config.StartDate) || (!config.EndDate.IsZero() && date.After(config.EndDate)) {
+				continue
+			}
+		case lookback <= 0:
+			//no window, so everything is used
+		default:
+			if date.After(end) {
+				end = date
+			}
+			if !date.After(end.Add(-lookback)) {
+				continue
 			}
 		}
+		browsers = append(browsers, Browser{
+			Date:                date,
+			BrowserFamily:       data[columns.BrowserFamily],
+			BrowserMajorVersion: data[columns.BrowserMajor],
+			OSFamily:            column(data, columns.OSFamily),
+			OSMajorVersion:      column(data, columns.OSMajor),
+			Count:               count,
+		})
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+	if summary.Skipped() > summary.Rows/2 {
+		return nil, summary, fmt.Errorf("%w, its format appears to have changed: %s", ErrParseBrowserData, summary)
 	}
 
-	//limit to about one year's worth of data
-	if length := len(browsers) - 1; length > 0 {
-		lastDate := browsers[length].Date
-		yearAgo = lastDate.AddDate(-1, 0, 0)
-		records := []Browser{}
-		for _, b := range browsers {
-			if b.Date.After(yearAgo) {
-				records = append(records, b)
-			}
+	if ranged || columns.aggregated() || lookback <= 0 {
+		return browsers, summary, nil
+	}
+	//entries scanned before the most recent one was seen may still be out of the window
+	cutoff := end.Add(-lookback)
+	records := browsers[:0]
+	for _, b := range browsers {
+		if b.Date.After(cutoff) {
+			records = append(records, b)
 		}
-		browsers = records
 	}
+	return records, summary, nil
+}
 
-	return
+//DeviceSource provides the device profiles the browsers are joined to
+type DeviceSource interface {
+	Load() ([]Device, error)
+}
+
+//DeviceSourceFunc adapts a function to a DeviceSource
+type DeviceSourceFunc func() ([]Device, error)
+
+//Load calls f
+func (f DeviceSourceFunc) Load() ([]Device, error) {
+	return f()
 }
 
-func loadDeviceDetails(file string) (devices []Device) {
-	if f, err := os.Open(file); err == nil {
-		defer f.Close()
-		if bytes, err := ioutil.ReadAll(f); err == nil {
-			json.Unmarshal(bytes, &devices)
+//SSLLabsDeviceFile is a DeviceSource reading a file in the JSON form of the SSL Labs getClients API, see LoadDeviceDetailsReader.
+//It is the default source, reading the downloaded data
+type SSLLabsDeviceFile string
+
+//Load reads the device profiles from the file
+func (file SSLLabsDeviceFile) Load() ([]Device, error) {
+	f, err := os.Open(string(file))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	devices, summary, err := LoadDeviceDetailsReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if summary.Dropped() > 0 {
+		logf("Dropped devices of %s: %s", file, summary)
+	}
+	return devices, nil
+}
+
+//DeviceSummary reports how many device profiles were read and why any were dropped as invalid
+type DeviceSummary struct {
+	Devices     int //device profiles read
+	NoSuites    int //devices without cipher suites
+	BadProtocol int //devices whose protocol bounds are missing or inverted
+}
+
+//Dropped is the number of invalid devices
+func (d DeviceSummary) Dropped() int {
+	return d.NoSuites + d.BadProtocol
+}
+
+func (d DeviceSummary) String() string {
+	return fmt.Sprintf("%d of %d devices dropped (%d without cipher suites, %d with bad protocol bounds)",
+		d.Dropped(), d.Devices, d.NoSuites, d.BadProtocol)
+}
+
+//LoadDeviceDetailsReader reads device profiles in the JSON form of the SSL Labs getClients API.
+//Devices without cipher suites or protocol bounds would contribute nothing to the statistics, so they are dropped and
+//counted in the summary. An error is returned if most devices are invalid, which suggests the upstream format has changed
+func LoadDeviceDetailsReader(r io.Reader) (devices []Device, summary DeviceSummary, err error) {
+	if err = json.NewDecoder(r).Decode(&devices); err != nil {
+		return nil, summary, fmt.Errorf("%w: %v", ErrParseDeviceData, err)
+	}
+	summary.Devices = len(devices)
+	valid := devices[:0]
+	for _, d := range devices {
+		switch {
+		case len(d.SuiteIds) == 0:
+			summary.NoSuites++
+		case d.LowestProtocol <= 0 || d.HighestProtocol < d.LowestProtocol:
+			summary.BadProtocol++
+		default:
+			valid = append(valid, d)
 		}
 	}
-	return
+	if summary.Dropped() > summary.Devices/2 {
+		return nil, summary, fmt.Errorf("%w, its format appears to have changed: %s", ErrParseDeviceData, summary)
+	}
+	return valid, summary, nil
 }
@@ -0,0 +1,667 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	SetLogger(nil)
+	os.Exit(m.Run())
+}
+
+// testVersions are the browser families and versions of testBrowsers: some collapse to the version of a device
+// profile, some are mobile families deduplicated to their desktop one, and some match no device profile
+var testVersions = []struct{ family, version string }{
+	{"Chrome", "70"},
+	{"Chrome", "72"},
+	{"Chrome Mobile", "70"},
+	{"Chrome", "63"},
+	{"Chrome", "69"},
+	{"Firefox", "63"},
+	{"Firefox Mobile", "60"},
+	{"Safari", "12"},
+	{"Mobile Safari", "12"},
+	{"IE", "11"},
+	{"IE", "8"},
+	{"Edge", "17"},
+	{"Samsung Internet", "8"},
+	{"Opera", "56"},
+	{"Other", "-"},
+}
+
+// testOSes are the OS families of testBrowsers
+var testOSes = []string{"Windows", "Mac OS X", "iOS", "Android", "Linux"}
+
+// testStart is the first date of testBrowsers
+var testStart = time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// testBrowsers is the given number of days of browser data, with a row for each of testVersions on each of testOSes
+func testBrowsers(days int) []Browser {
+	browsers := make([]Browser, 0, days*len(testVersions)*len(testOSes))
+	for d := 0; d < days; d++ {
+		for i, v := range testVersions {
+			for j, os := range testOSes {
+				browsers = append(browsers, Browser{
+					Date:                testStart.AddDate(0, 0, d),
+					OSFamily:            os,
+					OSMajorVersion:      "10",
+					BrowserFamily:       v.family,
+					BrowserMajorVersion: v.version,
+					Count:               int64(1000 + (7*d+13*i+31*j)%997),
+				})
+			}
+		}
+	}
+	return browsers
+}
+
+// testTSV is the browser data in the TSV form of the Wikipedia report
+func testTSV(browsers []Browser) string {
+	var sb strings.Builder
+	for _, b := range browsers {
+		fmt.Fprintf(&sb, "%s\t%s\t%s\t%s\t%s\t%d\n", b.Date.Format(dateFormat), b.OSFamily, b.OSMajorVersion,
+			b.BrowserFamily, b.BrowserMajorVersion, b.Count)
+	}
+	return sb.String()
+}
+
+// testDevices are device profiles for most of testVersions, as collapsed
+func testDevices() []Device {
+	modern := []int{0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xcca9, 0xc013, 0x2f, 0x35}
+	devices := []Device{
+		{Name: "Chrome", Version: "70", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS13,
+			SuiteIds: append([]int{0x0a0a}, modern...), EllipticCurves: []int{0x0a0a, 29, 23, 24}},
+		{Name: "Chrome", Version: "57", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS12,
+			SuiteIds: modern[3:], EllipticCurves: []int{29, 23, 24}},
+		{Name: "Firefox", Version: "62", LowestProtocol: tls.VersionTLS10, HighestProtocol: 0x7f1c,
+			SuiteIds: modern, EllipticCurves: []int{29, 23, 24, 25}},
+		{Name: "Firefox", Version: "59", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS12,
+			SuiteIds: modern[3:], EllipticCurves: []int{29, 23, 24, 25}},
+		{Name: "Safari", Version: "10", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS12,
+			SuiteIds: []int{0xc02c, 0xc02b, 0xc024, 0xc023, 0x9d, 0x9c, 0x3d, 0x3c, 0x35, 0x2f}, EllipticCurves: []int{29, 23, 24, 25}},
+		{Name: "IE", Version: "11", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS12,
+			SuiteIds: []int{0xc030, 0xc02f, 0xc028, 0xc027, 0x9f, 0x9e, 0x3d, 0x3c, 0x35, 0x2f, 0x0a}, EllipticCurves: []int{23, 24}},
+		{Name: "IE", Version: "8", LowestProtocol: tls.VersionSSL30, HighestProtocol: tls.VersionTLS10,
+			SuiteIds: []int{0x2f, 0x35, 0x05, 0x0a, 0x04}},
+		{Name: "Edge", Version: "15", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS12,
+			SuiteIds: []int{0xc02c, 0xc02b, 0xc030, 0xc02f, 0x9f, 0x9e, 0x3d, 0x3c, 0x35, 0x2f, 0x0a}, EllipticCurves: []int{29, 23, 24}},
+		{Name: "Opera", Version: "17", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS12,
+			SuiteIds: modern[3:], EllipticCurves: []int{23, 24}},
+	}
+	for i, d := range devices {
+		for _, id := range d.SuiteIds {
+			devices[i].SuiteNames = append(devices[i].SuiteNames, fmt.Sprintf("TLS_%04X", id))
+		}
+	}
+	return devices
+}
+
+// useTestData points the package at temporary directories holding the test data as if downloaded today, a day after
+// testBrowsers(days) ends, with a malformed row in the browser data so that each analysis logs that it skipped it
+func useTestData(t *testing.T, days int) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "tls-stats-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStats, oldData, oldOut, oldRaw := statsHome, dataHome, jsonStatsOut, jsonRawOut
+	statsHome, dataHome = path.Join(dir, "stats"), path.Join(dir, "data")
+	jsonStatsOut, jsonRawOut = path.Join(statsHome, "tls-stats-current.json"), path.Join(statsHome, "tls-stats-raw-current.json")
+	today := testStart.AddDate(0, 0, days)
+	SetClock(func() time.Time { return today })
+	InvalidateCache()
+	t.Cleanup(func() {
+		statsHome, dataHome, jsonStatsOut, jsonRawOut = oldStats, oldData, oldOut, oldRaw
+		SetClock(nil)
+		InvalidateCache()
+		os.RemoveAll(dir)
+	})
+
+	devices, err := json.Marshal(testDevices())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, data := range map[string]string{
+		browserStatsData(): testTSV(testBrowsers(days)) + "malformed\n",
+		deviceCiphers():    string(devices),
+	} {
+		if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(statsHome, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countingLogger counts the messages logged with each format
+type countingLogger struct {
+	sync.Mutex
+	counts map[string]int
+}
+
+func (l *countingLogger) Printf(format string, v ...interface{}) {
+	l.Lock()
+	defer l.Unlock()
+	if l.counts == nil {
+		l.counts = make(map[string]int)
+	}
+	l.counts[format]++
+}
+
+func (l *countingLogger) count(format string) int {
+	l.Lock()
+	defer l.Unlock()
+	return l.counts[format]
+}
+
+// referenceStats joins the browsers to the devices the straightforward way the analysis originally did, one browser
+// at a time with keys built by fmt.Sprintf, as a check on the optimised join
+func referenceStats(browsers []Browser, devices []Device, protocolFloor int) TLSStats {
+	deviceKeys := make(map[string]Device)
+	for _, d := range devices {
+		deviceKeys[fmt.Sprintf("%s:%s", d.Name, d.Version)] = d
+	}
+	counts := make(map[string]int64)
+	for _, b := range browsers {
+		key := fmt.Sprintf("%s:%s", dedupFamily(b.BrowserFamily), b.BrowserMajorVersion)
+		if fv := strings.Split(key, ":"); len(fv) == 2 {
+			if nv, present := versionTables[fv[0]][fv[1]]; present {
+				if fv[0] == "Samsung Internet" {
+					if cv, present := versionTables["Chrome"][nv]; present {
+						nv = cv
+					}
+					key = fmt.Sprintf("Chrome:%s", nv)
+				} else {
+					key = fmt.Sprintf("%s:%s", fv[0], nv)
+				}
+			}
+		}
+		if _, present := deviceKeys[key]; present {
+			counts[key] += b.Count
+		}
+	}
+
+	stats := TLSStats{
+		Protocols:    make(map[int]int64),
+		Ciphers:      make(map[int]int64),
+		Curves:       make(map[int]int64),
+		DeviceCounts: make(map[string]int64),
+	}
+	for key, c := range counts {
+		d := deviceKeys[key]
+		stats.Total += c
+		stats.DeviceCounts[key] += c
+		lowest, highest := normaliseProtocol(d.LowestProtocol), normaliseProtocol(d.HighestProtocol)
+		if highest < tls.VersionTLS12 {
+			stats.LegacyOnly += c
+		}
+		for _, p := range protocolVersions {
+			if p >= protocolFloor && p >= lowest && p <= highest {
+				stats.Protocols[p] += c
+			}
+		}
+		for _, id := range d.SuiteIds {
+			if !isGREASE(id) {
+				stats.Ciphers[id] += c
+			}
+		}
+		for _, id := range d.EllipticCurves {
+			if !isGREASE(id) {
+				stats.Curves[id] += c
+			}
+		}
+	}
+	return stats
+}
+
+// assertSameCounts fails the test if the counts of got and want differ
+func assertSameCounts(t *testing.T, got, want TLSStats) {
+	t.Helper()
+	if got.Total != want.Total {
+		t.Errorf("Total = %d, want %d", got.Total, want.Total)
+	}
+	if got.LegacyOnly != want.LegacyOnly {
+		t.Errorf("LegacyOnly = %d, want %d", got.LegacyOnly, want.LegacyOnly)
+	}
+	for _, m := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"Protocols", got.Protocols, want.Protocols},
+		{"Ciphers", got.Ciphers, want.Ciphers},
+		{"Curves", got.Curves, want.Curves},
+		{"DeviceCounts", got.DeviceCounts, want.DeviceCounts},
+	} {
+		if !reflect.DeepEqual(m.got, m.want) {
+			t.Errorf("%s = %v, want %v", m.name, m.got, m.want)
+		}
+	}
+}
+
+func TestAnalyseMatchesReference(t *testing.T) {
+	browsers := testBrowsers(365)
+	devices := testDevices()
+	config := DefaultConfig()
+	stats, _, _, err := analyse(context.Background(), browsers, devices, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSameCounts(t, stats, referenceStats(browsers, devices, config.ProtocolFloor))
+}
+
+func BenchmarkAnalyse(b *testing.B) {
+	browsers := testBrowsers(365)
+	devices := testDevices()
+	config := DefaultConfig()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := analyse(context.Background(), browsers, devices, config); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBrowserKeyerMatchesBrowserKey(t *testing.T) {
+	config := DefaultConfig()
+	config.VersionCollapse = map[string]map[string]string{"Chrome": {"69": "70"}}
+	browserKey := config.browserKeyer()
+	for _, b := range testBrowsers(2) {
+		if got, want := browserKey(b), config.browserKey(b); got != want {
+			t.Errorf("memoised key of %s %s = %s, want %s", b.BrowserFamily, b.BrowserMajorVersion, got, want)
+		}
+	}
+}
+
+func BenchmarkBrowserKey(b *testing.B) {
+	browsers := testBrowsers(365)
+	config := DefaultConfig()
+	b.Run("direct", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, browser := range browsers {
+				config.browserKey(browser)
+			}
+		}
+	})
+	b.Run("memoised", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			browserKey := config.browserKeyer()
+			for _, browser := range browsers {
+				browserKey(browser)
+			}
+		}
+	})
+}
+
+func TestParallelJoinMatchesSerial(t *testing.T) {
+	browsers := testBrowsers(365)
+	devices := testDevices()
+	config := DefaultConfig()
+	config.Workers = 1
+	serial, _, _, err := analyse(context.Background(), browsers, devices, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{2, 3, 8, 100} {
+		config.Workers = workers
+		parallel, _, _, err := analyse(context.Background(), browsers, devices, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(parallel, serial) {
+			t.Errorf("the stats with %d workers differ from those with one", workers)
+		}
+	}
+}
+
+func BenchmarkWorkers(b *testing.B) {
+	browsers := testBrowsers(365)
+	devices := testDevices()
+	for _, workers := range []int{1, 2, 4, 8} {
+		config := DefaultConfig()
+		config.Workers = workers
+		b.Run(fmt.Sprintf("%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, _, err := analyse(context.Background(), browsers, devices, config); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadBrowserStatsKeepsLastWindow(t *testing.T) {
+	browsers := testBrowsers(2 * 365)
+	reversed := make([]Browser, len(browsers))
+	for i, b := range browsers {
+		reversed[len(browsers)-1-i] = b
+	}
+	end := browsers[len(browsers)-1].Date
+	first := end.Add(-DefaultLookback).AddDate(0, 0, 1)
+	perDay := len(testVersions) * len(testOSes)
+	for name, rows := range map[string][]Browser{"oldest first": browsers, "newest first": reversed} {
+		loaded, _, err := LoadBrowserStatsReader(strings.NewReader(testTSV(rows)), DefaultLookback)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(loaded) != 365*perDay {
+			t.Errorf("%s: loaded %d rows, want the %d of the last 365 days", name, len(loaded), 365*perDay)
+		}
+		for _, b := range loaded {
+			if b.Date.Before(first) || b.Date.After(end) {
+				t.Errorf("%s: loaded a row of %s, outside %s to %s", name, b.Date.Format(dateFormat),
+					first.Format(dateFormat), end.Format(dateFormat))
+				break
+			}
+		}
+	}
+}
+
+func TestTLS13Counted(t *testing.T) {
+	for _, highest := range []int{tls.VersionTLS13, 0x7f17, 0x7f1c} {
+		devices := []Device{{Name: "Chrome", Version: "70", LowestProtocol: tls.VersionTLS10, HighestProtocol: highest}}
+		browsers := map[string]int64{"Chrome:70": 10}
+		stats := getTLSStats(browsers, devices, indexDevices(devices, BrowserOnlyKey), DefaultProtocolFloor, 1)
+		want := map[int]int64{tls.VersionTLS10: 10, tls.VersionTLS11: 10, tls.VersionTLS12: 10, tls.VersionTLS13: 10}
+		if !reflect.DeepEqual(stats.Protocols, want) {
+			t.Errorf("highest protocol %#x: protocols = %v, want %v", highest, stats.Protocols, want)
+		}
+		if stats.Highest[tls.VersionTLS13] != 10 {
+			t.Errorf("highest protocol %#x: not counted as TLS 1.3 in %v", highest, stats.Highest)
+		}
+	}
+}
+
+func TestSamsungInternetKey(t *testing.T) {
+	config := DefaultConfig()
+	for version, want := range map[string]string{
+		"8":  "Chrome:57", //based on Chromium 63, which collapses to 57
+		"10": "Chrome:70", //based on Chromium 71
+		"99": "Samsung Internet:99",
+	} {
+		b := Browser{BrowserFamily: "Samsung Internet", BrowserMajorVersion: version}
+		if got := config.browserKey(b); got != want {
+			t.Errorf("key of Samsung Internet %s = %s, want %s", version, got, want)
+		}
+	}
+}
+
+func TestAlternateDateLayout(t *testing.T) {
+	browsers := testBrowsers(3)
+	tsv := strings.NewReplacer("2018-01-01", "01/01/2018", "2018-01-02", "02/01/2018", "2018-01-03", "03/01/2018").
+		Replace(testTSV(browsers))
+	loaded, summary, err := loadBrowserStats(strings.NewReader(tsv), Config{Lookback: DefaultLookback, DateLayout: "02/01/2006"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Skipped() != 0 || !reflect.DeepEqual(loaded, browsers) {
+		t.Errorf("loaded %d of %d rows (%s)", len(loaded), len(browsers), summary)
+	}
+	if _, _, err := LoadBrowserStatsReader(strings.NewReader(tsv), DefaultLookback); !errors.Is(err, ErrParseBrowserData) {
+		t.Errorf("the default layout did not reject the dates: %v", err)
+	}
+}
+
+func TestDuplicateDeviceKeysMerged(t *testing.T) {
+	a := Device{Name: "Safari", Version: "10", LowestProtocol: tls.VersionTLS10, HighestProtocol: tls.VersionTLS12,
+		SuiteIds: []int{0xc02c, 0x2f}, SuiteNames: []string{"A", "B"}, EllipticCurves: []int{23}}
+	b := Device{Name: "Safari", Version: "10", LowestProtocol: tls.VersionSSL30, HighestProtocol: tls.VersionTLS11,
+		SuiteIds: []int{0x2f, 0x0a}, SuiteNames: []string{"B", "C"}, EllipticCurves: []int{24, 23}}
+	want := Device{Name: "Safari", Version: "10", LowestProtocol: tls.VersionSSL30, HighestProtocol: tls.VersionTLS12,
+		SuiteIds: []int{0xc02c, 0x2f, 0x0a}, SuiteNames: []string{"A", "B", "C"}, EllipticCurves: []int{23, 24}}
+	if got := indexDevices([]Device{a, b}, BrowserOnlyKey)["Safari:10"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("merged %v and %v into %v, want %v", a, b, got, want)
+	}
+	//the capabilities do not depend on the order, only the order of the suites and curves does
+	got := indexDevices([]Device{b, a}, BrowserOnlyKey)["Safari:10"]
+	if got.LowestProtocol != want.LowestProtocol || got.HighestProtocol != want.HighestProtocol ||
+		len(got.SuiteIds) != len(want.SuiteIds) || len(got.EllipticCurves) != len(want.EllipticCurves) {
+		t.Errorf("merged %v and %v into %v, want the capabilities of %v", b, a, got, want)
+	}
+}
+
+func TestConcurrentGetStats(t *testing.T) {
+	useTestData(t, 60)
+	logs := &countingLogger{}
+	SetLogger(logs)
+	defer SetLogger(nil)
+
+	results := make([]TLSStatistics, 20)
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = GetStatsWith(WithOffline())
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(results[i], results[0]) {
+			t.Errorf("caller %d got different statistics from caller 0", i)
+		}
+	}
+	if n := logs.count("Skipped rows of %s: %s"); n != 1 {
+		t.Errorf("analysed the data %d times, want once", n)
+	}
+
+	var want bytes.Buffer
+	if err := results[0].WriteJSON(&want); err != nil {
+		t.Fatal(err)
+	}
+	written, err := ioutil.ReadFile(jsonStatsOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, want.Bytes()) {
+		t.Errorf("%s does not hold the statistics returned", jsonStatsOut)
+	}
+}
+
+func TestCorruptStatistics(t *testing.T) {
+	useTestData(t, 60)
+	if err := ioutil.WriteFile(jsonStatsOut, []byte(`{"protocols": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetStatsWith(WithOffline()); err == nil {
+		t.Error("corrupt statistics were loaded without an error")
+	}
+}
+
+func TestDownloadFailure(t *testing.T) {
+	useTestData(t, 60)
+	for _, file := range []string{browserStatsData(), deviceCiphers()} {
+		if err := os.Remove(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	oldBrowsers, oldDevices := BrowserStats, DeviceDetails
+	BrowserStats, DeviceDetails = server.URL+"/browsers.tsv", server.URL+"/devices.json"
+	defer func() { BrowserStats, DeviceDetails = oldBrowsers, oldDevices }()
+
+	if _, err := GetStatsWith(); !errors.Is(err, ErrDownloadFailed) {
+		t.Errorf("GetStatsWith: got %v, want %v", err, ErrDownloadFailed)
+	}
+	if _, err := GetStatsByOS(false); !errors.Is(err, ErrDownloadFailed) {
+		t.Errorf("GetStatsByOS: got %v, want %v", err, ErrDownloadFailed)
+	}
+	if _, err := os.Stat(jsonStatsOut); !os.IsNotExist(err) {
+		t.Errorf("statistics were written despite the failed download: %v", err)
+	}
+}
+
+func TestDeviceSourceWithoutSuiteNames(t *testing.T) {
+	useTestData(t, 60)
+	source := DeviceSourceFunc(func() ([]Device, error) {
+		devices := testDevices()
+		for i := range devices {
+			devices[i].SuiteNames = nil
+		}
+		return devices, nil
+	})
+	statistics, err := AnalyseContext(context.Background(), WithOffline(), WithDeviceSource(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range statistics.Ciphers {
+		if e.Name == "" {
+			t.Errorf("cipher %#04x has no name", e.ID)
+		}
+	}
+}
+
+func TestPlatformFilterSplitsTraffic(t *testing.T) {
+	browsers := testBrowsers(60)
+	devices := testDevices()
+	for i := range devices {
+		devices[i].Platform = "Win 10"
+		if devices[i].Name == "Safari" {
+			devices[i].Platform = "iOS 12.1"
+		}
+	}
+	config := DefaultConfig()
+
+	config.PlatformFilter = []string{DesktopPlatform}
+	desktop, _, _, err := analyse(context.Background(), browsers, devices, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mobileRows := []Browser{}
+	for _, b := range browsers {
+		if b.OSFamily == "Android" || b.OSFamily == "iOS" {
+			mobileRows = append(mobileRows, b)
+		}
+	}
+	if _, _, _, err := analyse(context.Background(), mobileRows, devices, config); !errors.Is(err, ErrNoMatchingBrowsers) {
+		t.Errorf("Android and iOS browsers reached a desktop report: %v", err)
+	}
+	if desktop.DeviceCounts["Safari:10"] != 0 {
+		t.Errorf("iOS Safari profile counted in a desktop report: %v", desktop.DeviceCounts)
+	}
+
+	config.PlatformFilter = []string{MobilePlatform}
+	mobile, _, _, err := analyse(context.Background(), browsers, devices, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := int64(0)
+	for _, b := range browsers {
+		if (b.OSFamily == "iOS" || b.OSFamily == "Android") && strings.HasSuffix(b.BrowserFamily, "Safari") {
+			want += b.Count
+		}
+	}
+	if !reflect.DeepEqual(mobile.DeviceCounts, map[string]int64{"Safari:10": want}) {
+		t.Errorf("mobile report counted %v, want only the %d mobile Safari clients", mobile.DeviceCounts, want)
+	}
+}
+
+func TestFixedGenerationDateIsReproducible(t *testing.T) {
+	useTestData(t, 60)
+	generated := testStart.AddDate(1, 0, 0)
+	var first []byte
+	for i := 0; i < 2; i++ {
+		//a copy of the same data has another modification time
+		later := time.Now().Add(time.Duration(i) * time.Hour)
+		for _, file := range []string{browserStatsData(), deviceCiphers()} {
+			if err := os.Chtimes(file, later, later); err != nil {
+				t.Fatal(err)
+			}
+		}
+		statistics, err := AnalyseContext(context.Background(), WithOffline(), WithGenerationDate(generated))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := statistics.WriteJSON(&out); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = out.Bytes()
+		} else if !bytes.Equal(out.Bytes(), first) {
+			t.Error("the statistics of the same data with a fixed generation date differ")
+		}
+	}
+}
+
+func TestGetStatsContextCancelled(t *testing.T) {
+	useTestData(t, 60)
+	started := make(chan struct{}, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	oldBrowsers, oldDevices := BrowserStats, DeviceDetails
+	BrowserStats, DeviceDetails = server.URL+"/browsers.tsv", server.URL+"/devices.json"
+	defer func() { BrowserStats, DeviceDetails = oldBrowsers, oldDevices }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+	if _, err := GetStatsContext(ctx, WithForceDownload()); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetStatsContext: got %v, want %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(jsonStatsOut); !os.IsNotExist(err) {
+		t.Errorf("statistics were written despite the cancelled download: %v", err)
+	}
+	//nor is an analysis started once the context is done
+	if _, err := GetStatsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetStatsContext after cancelling: got %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestInterruptedDownload(t *testing.T) {
+	useTestData(t, 60)
+	file := browserStatsData()
+	if err := os.Remove(file); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//promise more than is sent, so that reading the body fails
+		w.Header().Set("Content-Length", "1000000")
+		w.Write([]byte("2018-01-01\tWindows\t10\tChrome\t70\t100\n"))
+	}))
+	defer server.Close()
+	source := dataSource{file: file, url: server.URL, dated: browserStatsFile}
+	if err := source.download(context.Background(), false); err == nil {
+		t.Fatal("the interrupted download did not fail")
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("the interrupted download left %s: %v", file, err)
+	}
+	if files, _ := ioutil.ReadDir(dataHome); len(files) != 1 {
+		t.Errorf("the interrupted download left temporary files: %d files in %s", len(files), dataHome)
+	}
+}
@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+//ClearData removes the downloaded data, browser-stats-*.tsv and device-ciphers-*.json, including the rolling browser
+//data, and any temporary files left by an interrupted download from the data directory. Other files there are left alone
+func ClearData() error {
+	files := []string{}
+	for _, pattern := range []string{browserStatsFile("*"), deviceCiphersFile("*"), path.Join(dataHome, "tls-stats-*.tmp")} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		files = append(files, matches...)
+	}
+	return removeFiles(files)
+}
+
+//ClearStats removes the backups of the statistics, tls-stats-<date>.json or .json.gz, and any temporary files left
+//by an interrupted generation from the statistics directory. Unless keepCurrent is set, the current statistics and
+//raw counts are removed too, so the next call to GetStats generates them afresh. Other files are left alone
+func ClearStats(keepCurrent bool) error {
+	statsMutex.Lock()
+	defer statsMutex.Unlock()
+	files := []string{}
+	backups, err := filepath.Glob(path.Join(statsHome, "tls-stats-*.json*"))
+	if err != nil {
+		return err
+	}
+	for _, file := range backups {
+		name := strings.TrimSuffix(strings.TrimSuffix(path.Base(file), compressedSuffix), ".json")
+		if _, e := time.Parse(dateFormat, strings.TrimPrefix(name, "tls-stats-")); e == nil {
+			files = append(files, file)
+		}
+	}
+	temporary, err := filepath.Glob(path.Join(statsHome, "tls-stats-*.tmp"))
+	if err != nil {
+		return err
+	}
+	files = append(files, temporary...)
+	if !keepCurrent {
+		files = append(files, jsonStatsOut, jsonStatsOut+compressedSuffix, jsonRawOut)
+		cache.valid = false
+	}
+	return removeFiles(files)
+}
+
+//removeFiles removes the regular files among files, carrying on past failures, whose errors are returned
+func removeFiles(files []string) error {
+	errs := []error{}
+	for _, file := range files {
+		info, err := os.Lstat(file)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if err = os.Remove(file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return joinErrors(errs)
+}
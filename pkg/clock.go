@@ -0,0 +1,30 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	clock      = time.Now
+	clockMutex sync.RWMutex
+)
+
+//SetClock replaces the package's clock, which dates the generated statistics, names the data downloaded today and
+//decides when statistics are stale, e.g. to test time-dependent behaviour deterministically. A nil clock restores
+//time.Now, the default
+func SetClock(c func() time.Time) {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	if c == nil {
+		c = time.Now
+	}
+	clock = c
+}
+
+//now is the time according to the package's clock
+func now() time.Time {
+	clockMutex.RLock()
+	defer clockMutex.RUnlock()
+	return clock()
+}
@@ -0,0 +1,497 @@
+package stats
+
+import (
+	"crypto/tls"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+//Config controls how the statistics are computed
+type Config struct {
+	Lookback       time.Duration //window of browser data, counting back from the most recent entry; all of it if not positive
+	PlatformFilter []string      //if not empty, only devices on one of these platforms are considered, see WithPlatformFilter
+
+	//StartDate and EndDate, if either is set, restrict the browser data to the inclusive range instead of the lookback
+	StartDate, EndDate time.Time
+	//MinDataDays is the fewest distinct dates of browser data the statistics are computed from, guarding against a
+	//truncated download. It should be lowered for shorter date ranges. 0 disables the check
+	MinDataDays int
+
+	//ProtocolFloor is the oldest protocol version counted: VersionSSL20 (0x0200) for SSL v2, tls.VersionSSL30 (0x0300),
+	//tls.VersionTLS10 (0x0301), tls.VersionTLS11 (0x0302), tls.VersionTLS12 (0x0303) or tls.VersionTLS13 (0x0304)
+	ProtocolFloor int
+
+	//FamilyCollapse maps browser families to the family they are counted as, e.g. "Chrome Mobile" to "Chrome".
+	//Families not in the map use the built-in mapping
+	FamilyCollapse map[string]string
+	//VersionCollapse maps a (collapsed) browser family to a map of major versions to the device profile version
+	//they are counted as. Versions not in the map use the built-in tables
+	VersionCollapse map[string]map[string]string
+
+	//Weights reweights the browser counts, e.g. towards the browser mix of a particular audience. Keys are matched
+	//against the collapsed browser key, first as family:version, e.g. "Chrome:70", and then as the family alone, e.g.
+	//"Chrome". Browsers matching neither keep their count. No weights leaves the Wikipedia distribution as is
+	Weights map[string]float64
+
+	//Proxies maps browsers matching no device profile, e.g. uncommon families or the "Other" bucket of the Wikipedia
+	//data, to the key of a device profile whose capabilities they are counted with, e.g. "Yandex Browser" to
+	//"Chrome:70". As with Weights, entries are matched against the collapsed browser key as family:version or family.
+	//The device keys are those of DeviceCounts
+	Proxies map[string]string
+
+	//KeyStrategy is how finely browsers are matched to device profiles: by browser alone, the default, or also by OS
+	KeyStrategy KeyStrategy
+
+	//Exclusions drops browsers, e.g. misclassified crawlers, before they are aggregated. As with Weights, entries are
+	//matched against the collapsed browser key, i.e. after family and version collapsing, as family:version or family
+	Exclusions []string
+
+	ForceDownload bool          //download the data and recompute the statistics even if they are recent
+	Offline       bool          //never download; use the data already downloaded
+	Staleness     time.Duration //age after which generated statistics are recomputed
+	CacheTTL      time.Duration //how long statistics are kept in memory before being read from disk again; 0 disables it
+
+	//BrowserFile and DeviceFile, if set, are the browser TSV and device JSON read as-is instead of the downloaded data.
+	//Nothing is downloaded when both are set
+	BrowserFile string
+	DeviceFile  string
+
+	//BrowserColumns, if set, is the layout of the browser data, e.g. BrowserOnlyColumns for the Wikipedia "by browser"
+	//report. Otherwise the columns are located by the header row, if any, or DefaultBrowserColumns is assumed.
+	//Without OS columns the browsers have no OS family
+	BrowserColumns *BrowserColumns
+	//DateLayout is the time.Parse layout of the dates in the browser data. It is independent of the dates in the names
+	//of the downloaded data and statistics backups, which are always formatted as 2006-01-02
+	DateLayout string
+
+	Compress bool //write the generated statistics, and hence their backups, gzip-compressed as tls-stats-*.json.gz
+	WriteRaw bool //also write the raw counts the statistics are computed from to tls-stats-raw-current.json
+	NoBackup bool //regenerate the statistics in place rather than first backing up the current ones as tls-stats-<date>.json
+
+	//StrictValidation fails the analysis, rather than logging a warning, if the statistics are implausible,
+	//see TLSStatistics.Validate
+	StrictValidation bool
+
+	//RollingData merges the downloaded browser data into the rolling browser data, which is analysed instead,
+	//see UpdateRollingBrowserStats
+	RollingData bool
+
+	//GenerationDate, if set, is the generation date of the statistics instead of today, so that the same data gives
+	//byte-identical statistics, e.g. for CI artifacts. The dates the data was fetched are then left zero, as they are
+	//the modification times of the files, which differ between copies of the same data. Statistics with an old
+	//generation date are regenerated as stale, see Staleness
+	GenerationDate time.Time
+
+	//DataDate, if set, selects the data downloaded on that day instead of today's. Nothing is downloaded
+	DataDate time.Time
+
+	//DeviceSource, if set, provides the device profiles instead of the SSL Labs data, in which case DeviceFile is unused
+	DeviceSource DeviceSource
+
+	//Workers is the number of goroutines counting the support of the matched browsers. If not positive,
+	//runtime.GOMAXPROCS is used
+	Workers int
+
+	//Denominator is the traffic the percentages are relative to, by default MatchedTraffic
+	Denominator Denominator
+}
+
+//Denominator is the traffic the percentages are relative to
+type Denominator int
+
+const (
+	//MatchedTraffic counts only the clients matching a device profile, so the percentages describe the clients whose
+	//support is known. This is the default
+	MatchedTraffic Denominator = iota
+	//ObservedTraffic counts all the clients, including those matching no device profile, which count as supporting
+	//nothing. The percentages are then lower bounds of the support over all traffic. Excluded clients are not counted
+	ObservedTraffic
+)
+
+//VersionSSL20 is the SSL v2 protocol version, as reported by SSL Labs. crypto/tls does not define it
+const VersionSSL20 = 0x0200
+
+//DefaultStaleness is the age after which generated statistics are recomputed, about six months
+var DefaultStaleness = 182 * 24 * time.Hour
+
+//DefaultCacheTTL is how long statistics are kept in memory before being read from disk again
+var DefaultCacheTTL = time.Hour
+
+//DefaultDateLayout is the layout of the dates in the Wikipedia browser data
+const DefaultDateLayout = "2006-01-02"
+
+//DefaultMinDataDays is the fewest distinct dates of browser data the statistics are computed from by default
+const DefaultMinDataDays = 30
+
+//DefaultProtocolFloor counts protocols from SSL v3 upwards
+const DefaultProtocolFloor = tls.VersionSSL30
+
+//protocolVersions are the protocol versions counted, oldest first
+var protocolVersions = []int{VersionSSL20, tls.VersionSSL30, tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+//DefaultConfig returns the configuration used when none is specified
+func DefaultConfig() Config {
+	return Config{
+		Lookback:      DefaultLookback,
+		ProtocolFloor: DefaultProtocolFloor,
+		Staleness:     DefaultStaleness,
+		CacheTTL:      DefaultCacheTTL,
+		DateLayout:    DefaultDateLayout,
+		MinDataDays:   DefaultMinDataDays,
+	}
+}
+
+//browserFile is the browser data to analyse: that of the DataDate, or else the rolling data if used, or today's or
+//the most recently downloaded
+func (config Config) browserFile() string {
+	if config.BrowserFile != "" {
+		return config.BrowserFile
+	}
+	if !config.DataDate.IsZero() {
+		return browserStatsFile(config.DataDate.Format(dateFormat))
+	}
+	if config.RollingData && fileExists(rollingBrowserStats) {
+		return rollingBrowserStats
+	}
+	return freshestDataFile(browserStatsData(), browserStatsFile)
+}
+
+//deviceFile is the device data to analyse, chosen as by browserFile
+func (config Config) deviceFile() string {
+	if config.DeviceFile != "" {
+		return config.DeviceFile
+	}
+	if !config.DataDate.IsZero() {
+		return deviceCiphersFile(config.DataDate.Format(dateFormat))
+	}
+	return freshestDataFile(deviceCiphers(), deviceCiphersFile)
+}
+
+//workers is the number of goroutines counting the support of the browsers
+func (config Config) workers() int {
+	if config.Workers > 0 {
+		return config.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+//deviceSource is where the device profiles come from
+func (config Config) deviceSource() DeviceSource {
+	if config.DeviceSource != nil {
+		return config.DeviceSource
+	}
+	return SSLLabsDeviceFile(config.deviceFile())
+}
+
+//defaultAnalysis reports whether the config analyses the downloaded data as the default config does, differing only
+//in how the statistics are generated, stored and cached. Only those statistics are kept as tls-stats-current.json
+func (config Config) defaultAnalysis() bool {
+	analysis, defaults := config, DefaultConfig()
+	for _, c := range []*Config{&analysis, &defaults} {
+		c.ForceDownload, c.Offline, c.Staleness, c.CacheTTL = false, false, 0, 0
+		c.Compress, c.WriteRaw, c.NoBackup, c.StrictValidation = false, false, false, false
+		c.MinDataDays, c.Workers = 0, 0
+		//neither the layout of the same data nor keeping its window as the rolling data changes the statistics
+		c.BrowserColumns, c.DateLayout, c.RollingData = nil, "", false
+	}
+	return reflect.DeepEqual(analysis, defaults)
+}
+
+//filterDevices keeps the devices matching the config's platform filter, see WithPlatformFilter
+func (config Config) filterDevices(devices []Device) []Device {
+	if len(config.PlatformFilter) == 0 {
+		return devices
+	}
+	filtered := []Device{}
+	for _, d := range devices {
+		names := []string{platformClass(d)}
+		if d.Platform != "" {
+			family, _ := platformOS(d.Platform)
+			names = append(names, family, d.Platform)
+		}
+		if config.matchesPlatform(names) {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+//platformIncluded reports whether the browser runs on a platform in the config's platform filter, if any: by the class
+//or OS family of the browser, or by an SSL Labs platform with the same OS family and major version.
+//Browsers on an unknown OS are not included by a filter
+func (config Config) platformIncluded(browser Browser) bool {
+	if len(config.PlatformFilter) == 0 {
+		return true
+	}
+	if config.matchesPlatform([]string{osClass(browser.OSFamily), browser.OSFamily}) {
+		return true
+	}
+	for _, p := range config.PlatformFilter {
+		if family, major := platformOS(p); family == browser.OSFamily && major != "" && major == browser.OSMajorVersion {
+			return true
+		}
+	}
+	return false
+}
+
+//matchesPlatform reports whether any of the names of a device's platform is in the config's platform filter
+func (config Config) matchesPlatform(names []string) bool {
+	for _, p := range config.PlatformFilter {
+		for _, name := range names {
+			if name != "" && strings.EqualFold(name, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//Option customises the Config used to compute the statistics
+type Option func(*Config)
+
+//WithLookback sets the window of browser data used, counting back from the most recent entry
+func WithLookback(lookback time.Duration) Option {
+	return func(config *Config) {
+		config.Lookback = lookback
+	}
+}
+
+//WithDateRange uses the browser data from start to end, inclusive, instead of the lookback window.
+//A zero end leaves the range open-ended
+func WithDateRange(start, end time.Time) Option {
+	return func(config *Config) {
+		config.StartDate = start
+		config.EndDate = end
+	}
+}
+
+//WithMinDataDays sets the fewest distinct dates of browser data the statistics are computed from, see Config.MinDataDays
+func WithMinDataDays(days int) Option {
+	return func(config *Config) {
+		config.MinDataDays = days
+	}
+}
+
+//WithPlatformFilter restricts the analysis to devices on one of the platforms, each of which is either a class of
+//platform, MobilePlatform or DesktopPlatform, an OS family as named in the Wikipedia data, e.g. Windows or iOS, or an
+//SSL Labs platform, e.g. "Win 10". Names are matched ignoring case. Both the browser traffic and the device profiles
+//are filtered, so that e.g. the Chrome Mobile traffic of Android, which is matched to the Chrome profiles, is not
+//counted in a desktop report. Browsers on an unknown OS, and devices without a platform, such as TLS libraries and
+//crawlers, match no filter, except the Android browser profiles, which are mobile
+func WithPlatformFilter(platforms ...string) Option {
+	return func(config *Config) {
+		config.PlatformFilter = platforms
+	}
+}
+
+//WithProtocolFloor sets the oldest protocol version counted
+func WithProtocolFloor(version int) Option {
+	return func(config *Config) {
+		config.ProtocolFloor = version
+	}
+}
+
+//WithForceDownload downloads the data and recomputes the statistics even if they are recent
+func WithForceDownload() Option {
+	return func(config *Config) {
+		config.ForceDownload = true
+	}
+}
+
+//WithOffline uses the data already downloaded instead of downloading it
+func WithOffline() Option {
+	return func(config *Config) {
+		config.Offline = true
+	}
+}
+
+//WithStaleness sets the age after which generated statistics are recomputed
+func WithStaleness(staleness time.Duration) Option {
+	return func(config *Config) {
+		config.Staleness = staleness
+	}
+}
+
+//WithCacheTTL sets how long statistics are kept in memory before being read from disk again
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(config *Config) {
+		config.CacheTTL = ttl
+	}
+}
+
+//WithWeights reweights the browser counts, see Config.Weights
+func WithWeights(weights map[string]float64) Option {
+	return func(config *Config) {
+		config.Weights = weights
+	}
+}
+
+//WithDataFiles reads the browser TSV and device JSON from the given paths instead of downloading them
+func WithDataFiles(browserTSV, deviceJSON string) Option {
+	return func(config *Config) {
+		config.BrowserFile = browserTSV
+		config.DeviceFile = deviceJSON
+	}
+}
+
+//WithDeviceSource takes the device profiles from source instead of the SSL Labs data
+func WithDeviceSource(source DeviceSource) Option {
+	return func(config *Config) {
+		config.DeviceSource = source
+	}
+}
+
+//WithDataDate analyses the data downloaded on the given day, see DataDates
+func WithDataDate(date time.Time) Option {
+	return func(config *Config) {
+		config.DataDate = date
+	}
+}
+
+//WithGenerationDate sets the generation date of the statistics, see Config.GenerationDate
+func WithGenerationDate(date time.Time) Option {
+	return func(config *Config) {
+		config.GenerationDate = date
+	}
+}
+
+//WithCompression writes the generated statistics gzip-compressed
+func WithCompression() Option {
+	return func(config *Config) {
+		config.Compress = true
+	}
+}
+
+//WithProxies counts browsers matching no device profile as the given device profiles, see Config.Proxies
+func WithProxies(proxies map[string]string) Option {
+	return func(config *Config) {
+		config.Proxies = proxies
+	}
+}
+
+//WithKeyStrategy sets how finely browsers are matched to device profiles, see KeyStrategy
+func WithKeyStrategy(strategy KeyStrategy) Option {
+	return func(config *Config) {
+		config.KeyStrategy = strategy
+	}
+}
+
+//WithExclusions drops the browsers with the given collapsed keys, see Config.Exclusions
+func WithExclusions(keys ...string) Option {
+	return func(config *Config) {
+		config.Exclusions = keys
+	}
+}
+
+//WithoutBackup regenerates the statistics in place, without backing up the current ones, e.g. while debugging
+func WithoutBackup() Option {
+	return func(config *Config) {
+		config.NoBackup = true
+	}
+}
+
+//WithStrictValidation fails the analysis if the statistics are implausible, see Config.StrictValidation
+func WithStrictValidation() Option {
+	return func(config *Config) {
+		config.StrictValidation = true
+	}
+}
+
+//WithRollingData maintains and analyses the rolling browser data, see Config.RollingData
+func WithRollingData() Option {
+	return func(config *Config) {
+		config.RollingData = true
+	}
+}
+
+//WithRawCounts also writes the raw counts the statistics are computed from, see TLSStats
+func WithRawCounts() Option {
+	return func(config *Config) {
+		config.WriteRaw = true
+	}
+}
+
+//WithBrowserColumns sets the layout of the browser data, see Config.BrowserColumns
+func WithBrowserColumns(columns BrowserColumns) Option {
+	return func(config *Config) {
+		config.BrowserColumns = &columns
+	}
+}
+
+//WithDateLayout sets the time.Parse layout of the dates in the browser data, e.g. "02/01/2006"
+func WithDateLayout(layout string) Option {
+	return func(config *Config) {
+		config.DateLayout = layout
+	}
+}
+
+//WithWorkers sets the number of goroutines counting the support of the browsers, see Config.Workers
+func WithWorkers(workers int) Option {
+	return func(config *Config) {
+		config.Workers = workers
+	}
+}
+
+//WithDenominator sets the traffic the percentages are relative to, see Denominator
+func WithDenominator(denominator Denominator) Option {
+	return func(config *Config) {
+		config.Denominator = denominator
+	}
+}
+
+//newConfig applies the options to the default config
+func newConfig(opts ...Option) Config {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+//excluded reports whether the browser with the given collapsed key is excluded from the analysis
+func (config Config) excluded(key string) bool {
+	if len(config.Exclusions) == 0 {
+		return false
+	}
+	family := strings.Split(key, ":")[0]
+	for _, e := range config.Exclusions {
+		if e == key || e == family {
+			return true
+		}
+	}
+	return false
+}
+
+//proxy is the key of the device profile the browser with the given collapsed key is counted as, if any
+func (config Config) proxy(key string) (string, bool) {
+	if len(config.Proxies) == 0 {
+		return "", false
+	}
+	proxy, present := config.Proxies[key]
+	if !present {
+		if family := strings.Split(key, ":")[0]; family != key {
+			proxy, present = config.Proxies[family]
+		}
+	}
+	return proxy, present
+}
+
+//weigh applies the weight of the browser with the given collapsed key to its count
+func (config Config) weigh(key string, count int64) int64 {
+	if len(config.Weights) == 0 {
+		return count
+	}
+	weight, present := config.Weights[key]
+	if !present {
+		if family := strings.Split(key, ":")[0]; family != key {
+			weight, present = config.Weights[family]
+		}
+	}
+	if !present {
+		return count
+	}
+	return int64(math.Round(weight * float64(count)))
+}
@@ -9,6 +9,12 @@ var (
 	ieVers      map[string]string
 	edgeVers    map[string]string
 
+	//samsungChromiumVers maps Samsung Internet major versions to the Chromium major version they are based on
+	samsungChromiumVers map[string]string
+
+	//versionTables are the version collapse tables by browser family, see RegisterVersionCollapse
+	versionTables map[string]map[string]string
+
 	//NamedCurves are named elliptic curve
 	//see https://www.iana.org/assignments/tls-parameters/tls-parameters.xml#tls-parameters-8
 	NamedCurves = map[uint16]string{
@@ -199,6 +205,11 @@ var (
 		0x00C4: "TLS_DHE_RSA_WITH_CAMELLIA_256_CBC_SHA256",
 		0x00C5: "TLS_DH_anon_WITH_CAMELLIA_256_CBC_SHA256",
 		0x00FF: "TLS_EMPTY_RENEGOTIATION_INFO_SCSV",
+		0x1301: "TLS_AES_128_GCM_SHA256",
+		0x1302: "TLS_AES_256_GCM_SHA384",
+		0x1303: "TLS_CHACHA20_POLY1305_SHA256",
+		0x1304: "TLS_AES_128_CCM_SHA256",
+		0x1305: "TLS_AES_128_CCM_8_SHA256",
 		0x5600: "TLS_FALLBACK_SCSV",
 		0xC001: "TLS_ECDH_ECDSA_WITH_NULL_SHA",
 		0xC002: "TLS_ECDH_ECDSA_WITH_RC4_128_SHA",
@@ -391,6 +402,130 @@ var (
 		0xD003: "TLS_ECDHE_PSK_WITH_AES_128_CCM_8_SHA256",
 		0xD005: "TLS_ECDHE_PSK_WITH_AES_128_CCM_SHA256",
 	}
+
+	//OpenSSLCipherNames are the OpenSSL names of the commonly used cipher suites in CipherSuiteMap, as listed by `openssl ciphers -V`.
+	//OpenSSL uses the IANA names for TLS 1.3 suites
+	OpenSSLCipherNames = map[uint16]string{
+		0x0001: "NULL-MD5",
+		0x0002: "NULL-SHA",
+		0x0003: "EXP-RC4-MD5",
+		0x0004: "RC4-MD5",
+		0x0005: "RC4-SHA",
+		0x0006: "EXP-RC2-CBC-MD5",
+		0x0007: "IDEA-CBC-SHA",
+		0x0008: "EXP-DES-CBC-SHA",
+		0x0009: "DES-CBC-SHA",
+		0x000A: "DES-CBC3-SHA",
+		0x0011: "EXP-EDH-DSS-DES-CBC-SHA",
+		0x0012: "EDH-DSS-DES-CBC-SHA",
+		0x0013: "EDH-DSS-DES-CBC3-SHA",
+		0x0014: "EXP-EDH-RSA-DES-CBC-SHA",
+		0x0015: "EDH-RSA-DES-CBC-SHA",
+		0x0016: "EDH-RSA-DES-CBC3-SHA",
+		0x0017: "EXP-ADH-RC4-MD5",
+		0x0018: "ADH-RC4-MD5",
+		0x0019: "EXP-ADH-DES-CBC-SHA",
+		0x001A: "ADH-DES-CBC-SHA",
+		0x001B: "ADH-DES-CBC3-SHA",
+		0x002F: "AES128-SHA",
+		0x0032: "DHE-DSS-AES128-SHA",
+		0x0033: "DHE-RSA-AES128-SHA",
+		0x0034: "ADH-AES128-SHA",
+		0x0035: "AES256-SHA",
+		0x0038: "DHE-DSS-AES256-SHA",
+		0x0039: "DHE-RSA-AES256-SHA",
+		0x003A: "ADH-AES256-SHA",
+		0x003B: "NULL-SHA256",
+		0x003C: "AES128-SHA256",
+		0x003D: "AES256-SHA256",
+		0x0040: "DHE-DSS-AES128-SHA256",
+		0x0041: "CAMELLIA128-SHA",
+		0x0044: "DHE-DSS-CAMELLIA128-SHA",
+		0x0045: "DHE-RSA-CAMELLIA128-SHA",
+		0x0067: "DHE-RSA-AES128-SHA256",
+		0x006A: "DHE-DSS-AES256-SHA256",
+		0x006B: "DHE-RSA-AES256-SHA256",
+		0x0084: "CAMELLIA256-SHA",
+		0x0087: "DHE-DSS-CAMELLIA256-SHA",
+		0x0088: "DHE-RSA-CAMELLIA256-SHA",
+		0x008A: "PSK-RC4-SHA",
+		0x008B: "PSK-3DES-EDE-CBC-SHA",
+		0x008C: "PSK-AES128-CBC-SHA",
+		0x008D: "PSK-AES256-CBC-SHA",
+		0x0096: "SEED-SHA",
+		0x0099: "DHE-DSS-SEED-SHA",
+		0x009A: "DHE-RSA-SEED-SHA",
+		0x009C: "AES128-GCM-SHA256",
+		0x009D: "AES256-GCM-SHA384",
+		0x009E: "DHE-RSA-AES128-GCM-SHA256",
+		0x009F: "DHE-RSA-AES256-GCM-SHA384",
+		0x00A2: "DHE-DSS-AES128-GCM-SHA256",
+		0x00A3: "DHE-DSS-AES256-GCM-SHA384",
+		0x00A8: "PSK-AES128-GCM-SHA256",
+		0x00A9: "PSK-AES256-GCM-SHA384",
+		0x1301: "TLS_AES_128_GCM_SHA256",
+		0x1302: "TLS_AES_256_GCM_SHA384",
+		0x1303: "TLS_CHACHA20_POLY1305_SHA256",
+		0x1304: "TLS_AES_128_CCM_SHA256",
+		0x1305: "TLS_AES_128_CCM_8_SHA256",
+		0xC002: "ECDH-ECDSA-RC4-SHA",
+		0xC003: "ECDH-ECDSA-DES-CBC3-SHA",
+		0xC004: "ECDH-ECDSA-AES128-SHA",
+		0xC005: "ECDH-ECDSA-AES256-SHA",
+		0xC007: "ECDHE-ECDSA-RC4-SHA",
+		0xC008: "ECDHE-ECDSA-DES-CBC3-SHA",
+		0xC009: "ECDHE-ECDSA-AES128-SHA",
+		0xC00A: "ECDHE-ECDSA-AES256-SHA",
+		0xC00C: "ECDH-RSA-RC4-SHA",
+		0xC00D: "ECDH-RSA-DES-CBC3-SHA",
+		0xC00E: "ECDH-RSA-AES128-SHA",
+		0xC00F: "ECDH-RSA-AES256-SHA",
+		0xC011: "ECDHE-RSA-RC4-SHA",
+		0xC012: "ECDHE-RSA-DES-CBC3-SHA",
+		0xC013: "ECDHE-RSA-AES128-SHA",
+		0xC014: "ECDHE-RSA-AES256-SHA",
+		0xC016: "AECDH-RC4-SHA",
+		0xC017: "AECDH-DES-CBC3-SHA",
+		0xC018: "AECDH-AES128-SHA",
+		0xC019: "AECDH-AES256-SHA",
+		0xC023: "ECDHE-ECDSA-AES128-SHA256",
+		0xC024: "ECDHE-ECDSA-AES256-SHA384",
+		0xC025: "ECDH-ECDSA-AES128-SHA256",
+		0xC026: "ECDH-ECDSA-AES256-SHA384",
+		0xC027: "ECDHE-RSA-AES128-SHA256",
+		0xC028: "ECDHE-RSA-AES256-SHA384",
+		0xC029: "ECDH-RSA-AES128-SHA256",
+		0xC02A: "ECDH-RSA-AES256-SHA384",
+		0xC02B: "ECDHE-ECDSA-AES128-GCM-SHA256",
+		0xC02C: "ECDHE-ECDSA-AES256-GCM-SHA384",
+		0xC02D: "ECDH-ECDSA-AES128-GCM-SHA256",
+		0xC02E: "ECDH-ECDSA-AES256-GCM-SHA384",
+		0xC02F: "ECDHE-RSA-AES128-GCM-SHA256",
+		0xC030: "ECDHE-RSA-AES256-GCM-SHA384",
+		0xC031: "ECDH-RSA-AES128-GCM-SHA256",
+		0xC032: "ECDH-RSA-AES256-GCM-SHA384",
+		0xC072: "ECDHE-ECDSA-CAMELLIA128-SHA256",
+		0xC073: "ECDHE-ECDSA-CAMELLIA256-SHA384",
+		0xC076: "ECDHE-RSA-CAMELLIA128-SHA256",
+		0xC077: "ECDHE-RSA-CAMELLIA256-SHA384",
+		0xC09C: "AES128-CCM",
+		0xC09D: "AES256-CCM",
+		0xC09E: "DHE-RSA-AES128-CCM",
+		0xC09F: "DHE-RSA-AES256-CCM",
+		0xC0A0: "AES128-CCM8",
+		0xC0A1: "AES256-CCM8",
+		0xC0AC: "ECDHE-ECDSA-AES128-CCM",
+		0xC0AD: "ECDHE-ECDSA-AES256-CCM",
+		0xC0AE: "ECDHE-ECDSA-AES128-CCM8",
+		0xC0AF: "ECDHE-ECDSA-AES256-CCM8",
+		0xCCA8: "ECDHE-RSA-CHACHA20-POLY1305",
+		0xCCA9: "ECDHE-ECDSA-CHACHA20-POLY1305",
+		0xCCAA: "DHE-RSA-CHACHA20-POLY1305",
+		0xCCAB: "PSK-CHACHA20-POLY1305",
+		0xCCAC: "ECDHE-PSK-CHACHA20-POLY1305",
+		0xCCAD: "DHE-PSK-CHACHA20-POLY1305",
+		0xCCAE: "RSA-PSK-CHACHA20-POLY1305",
+	}
 )
 
 func init() {
@@ -489,6 +624,16 @@ func init() {
 		"18": "15",
 	}
 
+	samsungChromiumVers = map[string]string{
+		"4":  "44",
+		"5":  "51",
+		"6":  "56",
+		"7":  "59",
+		"8":  "63",
+		"9":  "67",
+		"10": "71",
+	}
+
 	ieVers = map[string]string{
 		"4": "6",
 		"5": "6",
@@ -519,4 +664,15 @@ func init() {
 		"59": "17",
 		"60": "17",
 	}
+
+	versionTables = map[string]map[string]string{
+		"Chrome":           chromeVers,
+		"Firefox":          firefoxVers,
+		"Android":          androidVers,
+		"Safari":           safariVers,
+		"Opera":            operaVers,
+		"Edge":             edgeVers,
+		"IE":               ieVers,
+		"Samsung Internet": samsungChromiumVers,
+	}
 }
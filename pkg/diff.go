@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+//StatsDiff is the change in client support between two statistics snapshots
+type StatsDiff struct {
+	OldDate   time.Time //generation date of the old snapshot
+	NewDate   time.Time //generation date of the new snapshot
+	Protocols []EntryDiff
+	Ciphers   []EntryDiff
+	Curves    []EntryDiff
+}
+
+//EntryDiff is the change in support of a single protocol, cipher or curve
+type EntryDiff struct {
+	ID         int
+	Name       string
+	OldPercent float64
+	NewPercent float64
+	Delta      float64 //NewPercent - OldPercent
+	Added      bool    //only present in the new snapshot
+	Removed    bool    //only present in the old snapshot
+}
+
+//Diff compares two statistics snapshots, joining entries by ID within each category.
+//The differences are ordered by descending magnitude of change
+func Diff(old, new TLSStatistics) StatsDiff {
+	return StatsDiff{
+		OldDate:   old.GenerationDate,
+		NewDate:   new.GenerationDate,
+		Protocols: diffEntries(old.Protocols, new.Protocols),
+		Ciphers:   diffEntries(old.Ciphers, new.Ciphers),
+		Curves:    diffEntries(old.Curves, new.Curves),
+	}
+}
+
+func diffEntries(old, new []Entry) []EntryDiff {
+	diffs := make(map[int]*EntryDiff)
+	for _, e := range old {
+		diffs[e.ID] = &EntryDiff{ID: e.ID, Name: e.Name, OldPercent: e.Percent, Removed: true}
+	}
+	for _, e := range new {
+		if d, present := diffs[e.ID]; present {
+			d.Name = e.Name
+			d.NewPercent = e.Percent
+			d.Removed = false
+		} else {
+			diffs[e.ID] = &EntryDiff{ID: e.ID, Name: e.Name, NewPercent: e.Percent, Added: true}
+		}
+	}
+
+	out := []EntryDiff{}
+	for _, d := range diffs {
+		d.Delta = d.NewPercent - d.OldPercent
+		out = append(out, *d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		di, dj := math.Abs(out[i].Delta), math.Abs(out[j].Delta)
+		if di != dj {
+			return di > dj
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+type diffSection struct {
+	title string
+	diffs []EntryDiff
+}
+
+func (d StatsDiff) sections() []diffSection {
+	return []diffSection{
+		{"Protocols", d.Protocols},
+		{"Ciphers", d.Ciphers},
+		{"Curves", d.Curves},
+	}
+}
+
+func (e EntryDiff) status() string {
+	switch {
+	case e.Added:
+		return "added"
+	case e.Removed:
+		return "removed"
+	default:
+		return ""
+	}
+}
+
+func (d StatsDiff) String() (out string) {
+	out += fmt.Sprintf("Changes from %s to %s\n", d.OldDate.Format(dateFormat), d.NewDate.Format(dateFormat))
+	for _, s := range d.sections() {
+		out += fmt.Sprintf("%s\n=============\n", s.title)
+		for _, e := range s.diffs {
+			out += fmt.Sprintf("\t%5d\t%7.2f%%\t%7.2f%%\t%+7.2f%%\t%s %s\n", e.ID, 100*e.OldPercent, 100*e.NewPercent,
+				100*e.Delta, e.Name, e.status())
+		}
+	}
+	return
+}
+
+//Markdown renders the differences as GitHub-flavoured Markdown tables
+func (d StatsDiff) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Changes from %s to %s\n", d.OldDate.Format(dateFormat), d.NewDate.Format(dateFormat))
+	for _, s := range d.sections() {
+		fmt.Fprintf(&sb, "\n## %s\n\n| ID | Name | Old | New | Change | |\n|---:|:-----|----:|----:|-------:|:-|\n", s.title)
+		for _, e := range s.diffs {
+			fmt.Fprintf(&sb, "| %d | %s | %.2f%% | %.2f%% | %+.2f%% | %s |\n", e.ID, e.Name, 100*e.OldPercent,
+				100*e.NewPercent, 100*e.Delta, e.status())
+		}
+	}
+	return sb.String()
+}
@@ -1,50 +1,117 @@
 package stats
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 )
 
-//BrowserStats Wikipedia OS and Browser counts
+//BrowserStats Wikipedia OS and Browser counts.
+//BrowserStats and DeviceDetails should only be changed before the statistics are generated, not concurrently
 var BrowserStats = "https://analytics.wikimedia.org/datasets/periodic/reports/metrics/browser/all_sites_by_os_and_browser.tsv"
 
 //DeviceDetails SSLLabs clients cipher and protocol support information
 var DeviceDetails = "https://api.ssllabs.com/api/v3/getClients"
 
+//DeviceDetailsParams are query parameters added to the DeviceDetails URL, replacing any of the same name in it
+var DeviceDetailsParams map[string]string
+
 func init() {
 
 	//create data directories, if they don't exist
 	if _, err := os.Stat(statsHome); os.IsNotExist(err) {
 		if err2 := os.MkdirAll(statsHome, 0755); err2 != nil {
-			log.Println("Could not create the path ", statsHome)
+			logf("Could not create the path %s: %v", statsHome, err2)
 		}
 	}
 
 	if _, err := os.Stat(dataHome); os.IsNotExist(err) {
 		if err2 := os.MkdirAll(dataHome, 0755); err2 != nil {
-			log.Println("Could not create the path ", dataHome)
+			logf("Could not create the path %s: %v", dataHome, err2)
 		}
 	}
 }
 
+//DefaultLookback is the window of browser data, counting back from the most recent entry, used to compute the stats
+var DefaultLookback = 365 * 24 * time.Hour
+
 var (
-	dateFormat       = "2006-01-02"
-	home             = getHome()
-	statsHome        = path.Join(home, "stats")
-	dataHome         = path.Join(home, "data")
-	today            = time.Now().Format(dateFormat)
-	browserStatsData = path.Join(dataHome, fmt.Sprintf("browser-stats-%s.tsv", today))
-	deviceCiphers    = path.Join(dataHome, fmt.Sprintf("device-ciphers-%s.json", today))
-	jsonStatsOut     = path.Join(statsHome, "tls-stats-current.json")
+	dateFormat   = "2006-01-02" //of the dates in file names; see Config.DateLayout for those in the browser data
+	home         = getHome()
+	statsHome    = path.Join(home, "stats")
+	dataHome     = path.Join(home, "data")
+	jsonStatsOut = path.Join(statsHome, "tls-stats-current.json")
+	jsonRawOut   = path.Join(statsHome, "tls-stats-raw-current.json")
 )
 
+//browserStatsData and deviceCiphers are the paths the data downloaded today, according to the package's clock, is
+//stored at
+func browserStatsData() string {
+	return browserStatsFile(now().Format(dateFormat))
+}
+
+func deviceCiphers() string {
+	return deviceCiphersFile(now().Format(dateFormat))
+}
+
+//compressedSuffix is appended to the names of gzip-compressed statistics
+const compressedSuffix = ".gz"
+
+//CurrentStatsFile is the path of the most recently generated statistics
+func CurrentStatsFile() string {
+	return currentStatsFile()
+}
+
+//currentStatsFile is tls-stats-current.json, or its compressed form if only that exists
+func currentStatsFile() string {
+	if _, err := os.Stat(jsonStatsOut); os.IsNotExist(err) {
+		if _, err := os.Stat(jsonStatsOut + compressedSuffix); err == nil {
+			return jsonStatsOut + compressedSuffix
+		}
+	}
+	return jsonStatsOut
+}
+
+//browserStatsFile and deviceCiphersFile are the paths the data downloaded on the date, in dateFormat, is stored at
+func browserStatsFile(date string) string {
+	return path.Join(dataHome, fmt.Sprintf("browser-stats-%s.tsv", date))
+}
+
+func deviceCiphersFile(date string) string {
+	return path.Join(dataHome, fmt.Sprintf("device-ciphers-%s.json", date))
+}
+
+//DataDates lists the dates, oldest first, for which both browser and device data have been downloaded
+func DataDates() (dates []time.Time, err error) {
+	files, err := filepath.Glob(browserStatsFile("*"))
+	if err != nil {
+		return
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		name := path.Base(f)
+		date, e := time.Parse(dateFormat, strings.TrimSuffix(strings.TrimPrefix(name, "browser-stats-"), ".tsv"))
+		if e != nil {
+			continue
+		}
+		if _, e := os.Stat(deviceCiphersFile(date.Format(dateFormat))); e == nil {
+			dates = append(dates, date)
+		}
+	}
+	return
+}
+
 func getHome() (h string) {
 	h = ".tls-stats"
 	if hh, err := homedir.Expand("~/.tls-stats"); err == nil {
@@ -53,28 +120,141 @@ func getHome() (h string) {
 	return
 }
 
-func download(filename, url string, force bool) (err error) {
-	if _, err := os.Stat(filename); force || os.IsNotExist(err) {
-		if resp, err := http.Get(url); err == nil {
-			defer resp.Body.Close()
-			if file, err := os.Create(filename); err == nil {
-				defer file.Close()
-				_, err = io.Copy(file, resp.Body)
-			}
-		}
-	} else {
-		return fmt.Errorf("Downloading file %s, which already exists. Use -f flag to force download", filename)
+func download(ctx context.Context, filename, url string, force bool) error {
+	if _, err := os.Stat(filename); !force && !os.IsNotExist(err) {
+		return fmt.Errorf("%w: %s. Use -f flag to force download", ErrFileExists, filename)
 	}
-	return
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return &DownloadError{URL: url, Err: err}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return &DownloadError{URL: url, Err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &DownloadError{URL: url, Err: errors.New(resp.Status)}
+	}
+	//written to a temporary file first, so that an interrupted download is not taken for the data
+	return writeFile(filename, func(w io.Writer) error {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	})
 }
 
-//DownloadData downloads data needed to calculate cipher support probabilities
+//DownloadData downloads data needed to calculate cipher support probabilities.
+//Each source is downloaded even if another fails, and the errors of all that failed are returned
 func DownloadData(force bool) error {
-	if err := download(browserStatsData, BrowserStats, force); err != nil {
-		return err
+	errs := []error{}
+	for _, source := range dataSources() {
+		if err := source.download(context.Background(), force); err != nil {
+			errs = append(errs, err)
+		}
 	}
-	if err := download(deviceCiphers, DeviceDetails, force); err != nil {
-		return err
+	return joinErrors(errs)
+}
+
+type dataSource struct {
+	file, url string
+	err       error                    //error building the URL
+	dated     func(date string) string //the path of the data downloaded on a date
+}
+
+func dataSources() []dataSource {
+	deviceURL, err := deviceDetailsURL()
+	return []dataSource{
+		{browserStatsData(), BrowserStats, nil, browserStatsFile},
+		{deviceCiphers(), deviceURL, err, deviceCiphersFile},
+	}
+}
+
+func (source dataSource) download(ctx context.Context, force bool) error {
+	if source.err != nil {
+		return source.err
+	}
+	return download(ctx, source.file, source.url, force)
+}
+
+//freshestDataFile is file, the data downloaded today, if it exists. Otherwise it is the most recently downloaded data
+//of the same kind, if any, so that one source being unavailable does not stop the analysis
+func freshestDataFile(file string, dated func(date string) string) string {
+	if fileExists(file) {
+		return file
+	}
+	pattern := dated("*")
+	star := strings.Index(pattern, "*")
+	prefix, suffix := pattern[:star], pattern[star+1:]
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return file
+	}
+	freshest := file
+	latest := time.Time{}
+	for _, f := range files {
+		//only the dated downloads, not other files matching the pattern, such as the rolling data
+		date, e := time.Parse(dateFormat, strings.TrimSuffix(strings.TrimPrefix(f, prefix), suffix))
+		if e != nil || !date.After(latest) {
+			continue
+		}
+		freshest, latest = f, date
 	}
-	return nil
+	return freshest
+}
+
+//deviceDetailsURL is DeviceDetails with DeviceDetailsParams added
+func deviceDetailsURL() (string, error) {
+	if len(DeviceDetailsParams) == 0 {
+		return DeviceDetails, nil
+	}
+	u, err := url.Parse(DeviceDetails)
+	if err != nil {
+		return DeviceDetails, fmt.Errorf("invalid device details URL: %w", err)
+	}
+	query := u.Query()
+	for name, value := range DeviceDetailsParams {
+		if name == "" {
+			return DeviceDetails, errors.New("device details query parameter with an empty name")
+		}
+		query.Set(name, value)
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+//DownloadPlan describes what DownloadData would do for one of the data sources
+type DownloadPlan struct {
+	URL          string
+	File         string
+	Exists       bool   //the file has already been downloaded
+	WillDownload bool   //the file would be (re-)downloaded rather than skipped
+	Status       string //response status of a HEAD request to the URL, if checked
+	Error        string //error building the URL, or making the HEAD request if checked
+}
+
+//DryRunDownload reports what DownloadData(force) would fetch, without downloading anything.
+//If check is set, the URLs are checked with HEAD requests
+func DryRunDownload(force, check bool) (plans []DownloadPlan) {
+	for _, source := range dataSources() {
+		_, err := os.Stat(source.file)
+		plan := DownloadPlan{
+			URL:    source.url,
+			File:   source.file,
+			Exists: !os.IsNotExist(err),
+		}
+		plan.WillDownload = force || !plan.Exists
+		if source.err != nil {
+			plan.WillDownload = false
+			plan.Error = source.err.Error()
+		} else if check {
+			if resp, err := http.Head(source.url); err == nil {
+				resp.Body.Close()
+				plan.Status = resp.Status
+			} else {
+				plan.Error = err.Error()
+			}
+		}
+		plans = append(plans, plan)
+	}
+	return
 }
@@ -0,0 +1,80 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//The errors returned by the package can be told apart with errors.Is, e.g. errors.Is(err, ErrFileExists) to carry on
+//with the data already downloaded
+var (
+	//ErrFileExists is returned when downloading data that has already been downloaded, unless forced
+	ErrFileExists = errors.New("file already exists")
+	//ErrDownloadFailed is matched by the DownloadError of data that could not be downloaded
+	ErrDownloadFailed = errors.New("download failed")
+	//ErrParseBrowserData is returned when the browser data is not in a recognised format
+	ErrParseBrowserData = errors.New("malformed browser data")
+	//ErrParseDeviceData is returned when the device data is not in a recognised format
+	ErrParseDeviceData = errors.New("malformed device data")
+	//ErrNoData is returned when there is no usable data to analyse, e.g. no browsers or none for the DataDate
+	ErrNoData = errors.New("no usable data")
+	//ErrTooFewDays is returned when the browser data spans fewer dates than Config.MinDataDays
+	ErrTooFewDays = errors.New("too few days of browser data")
+	//ErrImplausibleStats is matched by the errors of TLSStatistics.Validate
+	ErrImplausibleStats = errors.New("implausible statistics")
+	//ErrNoMatchingBrowsers is returned when none of the browsers matches a device profile
+	ErrNoMatchingBrowsers = errors.New("no browser matches a device profile")
+)
+
+//joinedErrors are several errors, e.g. of each data source that failed to download. They match any of the errors
+type joinedErrors []error
+
+func (errs joinedErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+//Is reports whether any of the errors matches target
+func (errs joinedErrors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+//joinErrors is nil without errors, the error if there is only one, or else the joinedErrors
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return joinedErrors(errs)
+}
+
+//DownloadError describes data that could not be downloaded. It matches ErrDownloadFailed
+type DownloadError struct {
+	URL string
+	Err error //the network error or, for an unexpected response, its status
+}
+
+func (e *DownloadError) Error() string {
+	return fmt.Sprintf("downloading %s: %v", e.URL, e.Err)
+}
+
+//Unwrap returns the underlying error
+func (e *DownloadError) Unwrap() error {
+	return e.Err
+}
+
+//Is reports whether target is ErrDownloadFailed
+func (e *DownloadError) Is(target error) bool {
+	return target == ErrDownloadFailed
+}
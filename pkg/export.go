@@ -0,0 +1,120 @@
+package stats
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Category is one of the kinds of entries in the statistics
+type Category string
+
+//Categories of entries
+const (
+	ProtocolCategory Category = "protocol"
+	CipherCategory   Category = "cipher"
+	CurveCategory    Category = "curve"
+)
+
+type category struct {
+	name    Category
+	title   string
+	entries []Entry
+}
+
+func (t TLSStatistics) categories() []category {
+	return []category{
+		{ProtocolCategory, "Protocols", t.Protocols},
+		{CipherCategory, "Ciphers", t.Ciphers},
+		{CurveCategory, "Curves", t.Curves},
+	}
+}
+
+//Entries returns the entries of the category, or nil for an unknown category
+func (t TLSStatistics) Entries(c Category) []Entry {
+	for _, cat := range t.categories() {
+		if cat.name == c {
+			return cat.entries
+		}
+	}
+	return nil
+}
+
+//WriteJSON writes the statistics in the indented JSON form of tls-stats-current.json
+func (t TLSStatistics) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(t)
+}
+
+//WriteJSONGzip writes the statistics as WriteJSON does, gzip-compressed
+func (t TLSStatistics) WriteJSONGzip(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	if err := t.WriteJSON(gz); err != nil {
+		gz.Close()
+		return err
+	}
+	return gz.Close()
+}
+
+//WriteCSV writes the statistics as a single table with the columns category, id, name and percent
+func (t TLSStatistics) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"category", "id", "name", "percent"}); err != nil {
+		return err
+	}
+	for _, c := range t.categories() {
+		for _, e := range c.entries {
+			record := []string{string(c.name), strconv.Itoa(e.ID), e.Name, strconv.FormatFloat(e.Percent, 'f', -1, 64)}
+			if err := out.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+//WriteCSVFile writes the statistics in CSV form to the file at path, see WriteCSV
+func (t TLSStatistics) WriteCSVFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = t.WriteCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//Markdown renders the statistics as GitHub-flavoured Markdown tables
+func (t TLSStatistics) Markdown() string {
+	var sb strings.Builder
+	t.WriteMarkdown(&sb)
+	return sb.String()
+}
+
+//WriteMarkdown writes the statistics as GitHub-flavoured Markdown tables, one each for protocols, ciphers and curves
+func (t TLSStatistics) WriteMarkdown(w io.Writer) (err error) {
+	if _, err = fmt.Fprintf(w, "Generated %s from data between %s and %s\n", t.GenerationDate.Format(dateFormat),
+		t.StartDate.Format(dateFormat), t.EndDate.Format(dateFormat)); err != nil {
+		return
+	}
+	for _, c := range t.categories() {
+		if _, err = fmt.Fprintf(w, "\n## %s\n\n| ID | Name | Percent |\n|---:|:-----|--------:|\n", c.title); err != nil {
+			return
+		}
+		for _, e := range c.entries {
+			if _, err = fmt.Fprintf(w, "| %d | %s | %.2f%% |\n", e.ID, e.Name, 100*e.Percent); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
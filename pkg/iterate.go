@@ -0,0 +1,99 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+//EachProtocol calls fn with each protocol entry, most supported first, until it returns false
+func (t TLSStatistics) EachProtocol(fn func(Entry) bool) {
+	each(t.Protocols, fn)
+}
+
+//EachCipher calls fn with each cipher entry, most supported first, until it returns false
+func (t TLSStatistics) EachCipher(fn func(Entry) bool) {
+	each(t.Ciphers, fn)
+}
+
+//EachCurve calls fn with each curve entry, most supported first, until it returns false
+func (t TLSStatistics) EachCurve(fn func(Entry) bool) {
+	each(t.Curves, fn)
+}
+
+func each(entries []Entry, fn func(Entry) bool) {
+	for _, e := range entries {
+		if !fn(e) {
+			return
+		}
+	}
+}
+
+//entryCategories are the categories of the entry lists in the JSON form of the statistics, by field name
+var entryCategories = map[string]Category{
+	"protocols": ProtocolCategory,
+	"ciphers":   CipherCategory,
+	"curves":    CurveCategory,
+}
+
+//StreamEntries decodes statistics in the JSON form of tls-stats-current.json, which may be gzip-compressed, calling fn
+//with each entry and its category in the order they are written, until it returns false. Only one entry is held in
+//memory at a time, so large statistics can be scanned without loading them, see LoadStatisticsReader
+func StreamEntries(r io.Reader, fn func(Category, Entry) bool) (err error) {
+	if r, err = decompressed(r); err != nil {
+		return fmt.Errorf("malformed statistics: %w", err)
+	}
+	dec := json.NewDecoder(r)
+	if err = expectDelim(dec, '{'); err != nil {
+		return
+	}
+	for dec.More() {
+		token, e := dec.Token()
+		if e != nil {
+			return fmt.Errorf("malformed statistics: %w", e)
+		}
+		name, _ := token.(string)
+		category, present := entryCategories[name]
+		if !present {
+			var skipped json.RawMessage
+			if err = dec.Decode(&skipped); err != nil {
+				return fmt.Errorf("malformed statistics: %w", err)
+			}
+			continue
+		}
+		if token, err = dec.Token(); err != nil {
+			return fmt.Errorf("malformed statistics: %w", err)
+		}
+		if token == nil {
+			continue //null
+		}
+		if token != json.Delim('[') {
+			return fmt.Errorf("malformed statistics: %s is not a list", category)
+		}
+		for dec.More() {
+			var entry Entry
+			if err = dec.Decode(&entry); err != nil {
+				return fmt.Errorf("malformed statistics: %w", err)
+			}
+			if !fn(category, entry) {
+				return nil
+			}
+		}
+		if err = expectDelim(dec, ']'); err != nil {
+			return
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+//expectDelim reads the next token, failing unless it is the delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("malformed statistics: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("malformed statistics: expected %s, found %v", delim, token)
+	}
+	return nil
+}
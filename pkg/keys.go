@@ -0,0 +1,139 @@
+package stats
+
+import "strings"
+
+//KeyStrategy is how finely browsers are matched to device profiles
+type KeyStrategy int
+
+const (
+	//BrowserOnlyKey matches browsers by family and version alone, e.g. Chrome:70, whatever their OS. This is the default
+	BrowserOnlyKey KeyStrategy = iota
+	//BrowserOSKey also matches the OS family of the browser to the platform of the device profile, e.g.
+	//Chrome:70@Windows, so a browser is only counted with the capabilities of the same browser on its OS
+	BrowserOSKey
+	//BrowserOSVersionKey also matches the OS major version, e.g. Chrome:70@Windows 10
+	BrowserOSVersionKey
+)
+
+//platformFamilies map the prefixes of the SSL Labs platforms to the OS families of the Wikipedia data, longest first.
+//They are matched ignoring case, as SSL Labs is not consistent, e.g. "macOS" and "MacOS"
+var platformFamilies = []struct{ prefix, family string }{
+	{"Windows Phone", "Windows Phone"},
+	{"Win Phone", "Windows Phone"},
+	{"Windows", "Windows"},
+	{"Win", "Windows"},
+	{"XP", "Windows"},
+	{"Vista", "Windows"},
+	{"Mac OS X", "Mac OS X"},
+	{"OS X", "Mac OS X"},
+	{"macOS", "Mac OS X"},
+	{"iOS", "iOS"},
+	{"Android", "Android"},
+}
+
+//platformOS is the OS family and major version, as named in the Wikipedia data, of an SSL Labs platform,
+//e.g. Windows and 8.1 for "Win 8.1", or Mac OS X and 10 for "OS X 10.11"
+func platformOS(platform string) (family, major string) {
+	family = platform
+	version := ""
+	for _, p := range platformFamilies {
+		if len(platform) >= len(p.prefix) && strings.EqualFold(platform[:len(p.prefix)], p.prefix) {
+			family = p.family
+			version = strings.TrimSpace(platform[len(p.prefix):])
+			if p.prefix == "XP" || p.prefix == "Vista" {
+				version = p.prefix
+			}
+			break
+		}
+	}
+	if i := strings.IndexByte(version, ' '); i >= 0 {
+		version = version[:i]
+	}
+	if family != "Windows" {
+		//the Wikipedia data only has the major version, except for Windows, e.g. 8.1
+		if i := strings.IndexByte(version, '.'); i >= 0 {
+			version = version[:i]
+		}
+	}
+	return family, version
+}
+
+//osKey is the OS part of a key under the strategy
+func (s KeyStrategy) osKey(family, major string) string {
+	if s == BrowserOSVersionKey {
+		return family + " " + major
+	}
+	return family
+}
+
+//deviceIndexKey is the key of a device under the strategy. Devices without a platform are indexed by their name and
+//version alone, as they describe the browser on any OS
+func (s KeyStrategy) deviceIndexKey(device Device) string {
+	key := deviceKey(device)
+	if s == BrowserOnlyKey || device.Platform == "" {
+		return key
+	}
+	return key + "@" + s.osKey(platformOS(device.Platform))
+}
+
+//matchDevice is the key of the device the browser, with the collapsed key, matches under the config's KeyStrategy:
+//the device on the browser's OS if there is one, or else one without a platform, or else its proxy, if any
+func (config Config) matchDevice(deviceKeys map[string]Device, key string, browser Browser) (string, bool) {
+	if config.KeyStrategy != BrowserOnlyKey {
+		osKey := key + "@" + config.KeyStrategy.osKey(browser.OSFamily, browser.OSMajorVersion)
+		if _, present := deviceKeys[osKey]; present {
+			return osKey, true
+		}
+	}
+	if _, present := deviceKeys[key]; present {
+		return key, true
+	}
+	if proxy, present := config.proxy(key); present {
+		if _, present := deviceKeys[proxy]; present {
+			return proxy, true
+		}
+	}
+	return key, false
+}
+
+//The classes of platform a device can be filtered by, see WithPlatformFilter
+const (
+	MobilePlatform  = "Mobile"
+	DesktopPlatform = "Desktop"
+)
+
+//mobileFamilies are the OS families, as named in the Wikipedia data, of mobile platforms
+var mobileFamilies = map[string]bool{
+	"iOS":           true,
+	"Android":       true,
+	"Windows Phone": true,
+	"BlackBerry OS": true,
+	"Symbian OS":    true,
+	"KaiOS":         true,
+}
+
+//osClass is MobilePlatform for mobile OS families, DesktopPlatform for the others, or empty if the family is
+//unknown, such as the "Other" family of the Wikipedia data
+func osClass(family string) string {
+	switch {
+	case mobileFamilies[family]:
+		return MobilePlatform
+	case family == "" || family == otherFamily:
+		return ""
+	}
+	return DesktopPlatform
+}
+
+//platformClass is the class of the device's platform, see osClass, or empty for those without a platform, such as
+//TLS libraries and crawlers. The Android devices have no platform, being the Android browser, so they are
+//recognised by name
+func platformClass(device Device) string {
+	if device.Name == "Android" {
+		return MobilePlatform
+	}
+	if device.Platform == "" {
+		return ""
+	}
+	family, _ := platformOS(device.Platform)
+	return osClass(family)
+}
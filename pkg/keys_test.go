@@ -0,0 +1,28 @@
+package stats
+
+import "testing"
+
+func TestPlatformOS(t *testing.T) {
+	for _, test := range []struct{ platform, family, major string }{
+		{"Win 10", "Windows", "10"},
+		{"Win 8.1", "Windows", "8.1"},
+		{"Win 7", "Windows", "7"},
+		{"XP SP3", "Windows", "XP"},
+		{"Vista", "Windows", "Vista"},
+		{"Windows 10", "Windows", "10"},
+		{"Win Phone 8.1", "Windows Phone", "8"},
+		{"OS X 10.11", "Mac OS X", "10"},
+		{"macOS 10.14", "Mac OS X", "10"},
+		{"MacOS 10.14.6 Beta", "Mac OS X", "10"},
+		{"Mac OS X 10.6.8", "Mac OS X", "10"},
+		{"iOS 12.1", "iOS", "12"},
+		{"IOS 9", "iOS", "9"},
+		{"Android 9.0", "Android", "9"},
+		{"Ubuntu", "Ubuntu", ""},
+		{"", "", ""},
+	} {
+		if family, major := platformOS(test.platform); family != test.family || major != test.major {
+			t.Errorf("platformOS(%q) = %q, %q, want %q, %q", test.platform, family, major, test.family, test.major)
+		}
+	}
+}
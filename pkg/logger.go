@@ -0,0 +1,37 @@
+package stats
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+//Logger receives the package's diagnostics, such as skipped data and unknown browser profiles. *log.Logger satisfies it
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var (
+	logger      Logger = log.New(os.Stderr, "", log.LstdFlags)
+	loggerMutex sync.RWMutex
+)
+
+//SetLogger routes the package's diagnostics to l. A nil Logger discards them. The default logs to standard error
+func SetLogger(l Logger) {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+	if l == nil {
+		l = nopLogger{}
+	}
+	logger = l
+}
+
+func logf(format string, v ...interface{}) {
+	loggerMutex.RLock()
+	defer loggerMutex.RUnlock()
+	logger.Printf(format, v...)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(format string, v ...interface{}) {}
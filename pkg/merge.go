@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+//Merge combines statistics, e.g. from different regions, into one. Entry counts are summed by ID within each category
+//and the percentages recomputed against the summed Total, so each input is weighted by its Total.
+//Inputs without a Total, such as those written before counts were recorded, cannot be weighted and are rejected.
+//Entries lacking a Count have it estimated from their Percent
+func Merge(stats ...TLSStatistics) (merged TLSStatistics, err error) {
+	if len(stats) == 0 {
+		return merged, errors.New("no statistics to merge")
+	}
+	legacyOnly, downgradable := 0.0, 0.0
+	highest := make(map[int]float64)
+	protocols := make(map[int]*Entry)
+	ciphers := make(map[int]*Entry)
+	curves := make(map[int]*Entry)
+	for i, s := range stats {
+		if s.Total <= 0 {
+			return merged, fmt.Errorf("statistics %d has no total to weigh it by", i)
+		}
+		merged.Total += s.Total
+		legacyOnly += s.LegacyOnly * float64(s.Total)
+		downgradable += s.Downgradable * float64(s.Total)
+		for p, fraction := range s.HighestProtocols {
+			highest[p] += fraction * float64(s.Total)
+		}
+		merged.Quality.Records += s.Quality.Records
+		merged.Quality.Matched += s.Quality.Matched
+		merged.Quality.Unmatched += s.Quality.Unmatched
+		merged.Quality.Excluded += s.Quality.Excluded
+		merged.Quality.Other += s.Quality.Other
+		for family, count := range s.Quality.Uncollapsed {
+			if merged.Quality.Uncollapsed == nil {
+				merged.Quality.Uncollapsed = make(map[string]int64)
+			}
+			merged.Quality.Uncollapsed[family] += count
+		}
+		merged.Quality.MatchedCount += s.Quality.MatchedCount
+		merged.Quality.UnmatchedCount += s.Quality.UnmatchedCount
+		if merged.StartDate.IsZero() || (!s.StartDate.IsZero() && s.StartDate.Before(merged.StartDate)) {
+			merged.StartDate = s.StartDate
+		}
+		if s.EndDate.After(merged.EndDate) {
+			merged.EndDate = s.EndDate
+		}
+		if s.GenerationDate.After(merged.GenerationDate) {
+			merged.GenerationDate = s.GenerationDate
+		}
+		for key, count := range s.DeviceCounts {
+			if merged.DeviceCounts == nil {
+				merged.DeviceCounts = make(map[string]int64)
+			}
+			merged.DeviceCounts[key] += count
+		}
+		mergeEntries(protocols, s.Protocols, s.Total)
+		mergeEntries(ciphers, s.Ciphers, s.Total)
+		mergeEntries(curves, s.Curves, s.Total)
+	}
+	merged.SchemaVersion = SchemaVersion
+	merged.LegacyOnly = legacyOnly / float64(merged.Total)
+	merged.Downgradable = downgradable / float64(merged.Total)
+	for p, weighted := range highest {
+		if merged.HighestProtocols == nil {
+			merged.HighestProtocols = make(map[int]float64)
+		}
+		merged.HighestProtocols[p] = weighted / float64(merged.Total)
+	}
+	merged.Protocols = mergedEntries(protocols, merged.Total)
+	merged.Ciphers = mergedEntries(ciphers, merged.Total)
+	setCumulative(merged.Ciphers)
+	merged.Curves = mergedEntries(curves, merged.Total)
+	return
+}
+
+//mergeEntries adds the counts of entries to those in merged, keeping the other fields of the first entry seen for an ID
+func mergeEntries(merged map[int]*Entry, entries []Entry, total int64) {
+	for _, e := range entries {
+		count := e.Count
+		if count == 0 {
+			count = int64(math.Round(e.Percent * float64(total)))
+		}
+		if m, present := merged[e.ID]; present {
+			m.Count += count
+		} else {
+			entry := e
+			entry.Count = count
+			merged[e.ID] = &entry
+		}
+	}
+}
+
+//mergedEntries returns the entries with percentages relative to total, ordered as in toJSONStruct
+func mergedEntries(merged map[int]*Entry, total int64) []Entry {
+	entries := []Entry{}
+	for _, e := range merged {
+		e.Percent = float64(e.Count) / float64(total)
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
@@ -2,19 +2,75 @@ package stats
 
 import (
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+//SchemaVersion is the version of the JSON form of TLSStatistics, bumped whenever its shape changes.
+//Statistics written before the schema was versioned have no version, i.e. 0, and name their multi-word fields
+//in Go style. Version 1 named the fields in snake case and version 2 added the total and the raw counts of the
+//entries. Version 3 added the class of the curves, the fetch dates of the data, the cumulative shares, the quality
+//report, the legacy-only, downgradable and per-device counts and the highest protocols
+const SchemaVersion = 3
+
 //TLSStatistics for JSON output
 type TLSStatistics struct {
-	GenerationDate time.Time //date this stats was generated
-	StartDate      time.Time //the date of first entry used to calculate the stats
-	EndDate        time.Time //the date of last entry used to calculate the stats
-	Protocols      []Entry
-	Ciphers        []Entry
-	Curves         []Entry
+	SchemaVersion  int       `json:"schema_version"`
+	GenerationDate time.Time `json:"generation_date"` //date this stats was generated
+	StartDate      time.Time `json:"start_date"`      //the date of first entry used to calculate the stats
+	EndDate        time.Time `json:"end_date"`        //the date of last entry used to calculate the stats
+	Total          int64     `json:"total"`           //the weighted number of clients the percentages are relative to
+	Protocols      []Entry   `json:"protocols"`
+	Ciphers        []Entry   `json:"ciphers"`
+	Curves         []Entry   `json:"curves"`
+
+	//BrowserDataFetched and DeviceDataFetched are when the browser and device data were downloaded, i.e. the
+	//modification times of the files analysed. They are zero when the data was not read from file, or when the
+	//generation date was fixed, see Config.GenerationDate
+	BrowserDataFetched time.Time `json:"browser_data_fetched"`
+	DeviceDataFetched  time.Time `json:"device_data_fetched"`
+
+	Quality QualityReport `json:"quality"` //how well the browser data joined to the device profiles
+
+	//LegacyOnly is the fraction of clients whose highest protocol is older than TLS 1.2, i.e. those locked out by
+	//requiring TLS 1.2 or newer. Unlike the protocol entries it accounts for each client's full protocol range
+	LegacyOnly float64 `json:"legacy_only"`
+	//Downgradable is the fraction of clients that support TLS 1.2 or newer but also still SSL v3 or TLS 1.0, and so
+	//could be downgraded by an attacker if the server allows those. Disabling them server-side protects these clients
+	Downgradable float64 `json:"downgradable"`
+	//HighestProtocols is the fraction of clients by the highest protocol they support. Unlike the protocol entries,
+	//each client is counted once, so the fractions add up to 1, or the match rate with the ObservedTraffic denominator
+	HighestProtocols map[int]float64 `json:"highest_protocols,omitempty"`
+
+	//DeviceCounts is the weighted number of clients matched to each device profile, keyed by name:version as in the
+	//SSL Labs data, e.g. "Chrome:70", followed by the OS when matched by OS, e.g. "Chrome:70@Windows", see KeyStrategy.
+	//Together with the device profiles this answers capability-set queries
+	DeviceCounts map[string]int64 `json:"device_counts,omitempty"`
+}
+
+//UnmarshalJSON also reads the unversioned form, whose multi-word fields used the Go field names
+func (t *TLSStatistics) UnmarshalJSON(data []byte) error {
+	type statistics TLSStatistics //without this method
+	if err := json.Unmarshal(data, (*statistics)(t)); err != nil {
+		return err
+	}
+	if t.SchemaVersion == 0 {
+		var legacy struct {
+			GenerationDate time.Time
+			StartDate      time.Time
+			EndDate        time.Time
+		}
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return err
+		}
+		t.GenerationDate, t.StartDate, t.EndDate = legacy.GenerationDate, legacy.StartDate, legacy.EndDate
+	}
+	return nil
 }
 
 //ToMapped generates a version of TLSStatistics with easy 'lookup'
@@ -25,8 +81,10 @@ func (t TLSStatistics) ToMapped() (m MappedTLSStatistics) {
 	}
 	m.Protocols = data
 	data = make(map[int]Entry)
+	m.cipherNames = make(map[string]int)
 	for _, x := range t.Ciphers {
 		data[x.ID] = x
+		m.cipherNames[x.Name] = x.ID
 	}
 	m.Ciphers = data
 	data = make(map[int]Entry)
@@ -34,21 +92,187 @@ func (t TLSStatistics) ToMapped() (m MappedTLSStatistics) {
 		data[x.ID] = x
 	}
 	m.Curves = data
+	m.LegacyOnly = t.LegacyOnly
+	m.Downgradable = t.Downgradable
 	return
 }
 
+//TopProtocols returns the n most supported protocols
+func (t TLSStatistics) TopProtocols(n int) []Entry {
+	return topEntries(t.Protocols, n)
+}
+
+//TopCiphers returns the n most supported ciphers
+func (t TLSStatistics) TopCiphers(n int) []Entry {
+	return topEntries(t.Ciphers, n)
+}
+
+//TopCurves returns the n most supported curves
+func (t TLSStatistics) TopCurves(n int) []Entry {
+	return topEntries(t.Curves, n)
+}
+
+//topEntries relies on the entries being sorted by descending support, as generated
+func topEntries(entries []Entry, n int) []Entry {
+	if n <= 0 {
+		return []Entry{}
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+	top := make([]Entry, n)
+	copy(top, entries)
+	return top
+}
+
+//HighestProtocolDistribution returns the fraction of clients by the highest protocol they support, e.g. the share of
+//clients dropped by requiring TLS 1.2 is that of the versions below it
+func (t TLSStatistics) HighestProtocolDistribution() map[int]float64 {
+	distribution := make(map[int]float64, len(t.HighestProtocols))
+	for p, fraction := range t.HighestProtocols {
+		distribution[p] = fraction
+	}
+	return distribution
+}
+
+//Filter returns a copy of the statistics containing only the protocols, ciphers and curves supported by at least minPercent of clients
+func (t TLSStatistics) Filter(minPercent float64) TLSStatistics {
+	t.Protocols = filterEntries(t.Protocols, minPercent)
+	t.Ciphers = filterEntries(t.Ciphers, minPercent)
+	t.Curves = filterEntries(t.Curves, minPercent)
+	return t
+}
+
+func filterEntries(entries []Entry, minPercent float64) []Entry {
+	filtered := []Entry{}
+	for _, e := range entries {
+		if e.Percent >= minPercent {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
 //MappedTLSStatistics is a version of TLSStatistics in 'Map' form
 type MappedTLSStatistics struct {
-	Protocols map[int]Entry
-	Ciphers   map[int]Entry
-	Curves    map[int]Entry
+	Protocols map[int]Entry `json:"protocols"`
+	Ciphers   map[int]Entry `json:"ciphers"`
+	Curves    map[int]Entry `json:"curves"`
+
+	LegacyOnly   float64 `json:"legacy_only"`  //see TLSStatistics.LegacyOnly
+	Downgradable float64 `json:"downgradable"` //see TLSStatistics.Downgradable
+
+	cipherNames map[string]int //cipher name to ID index
+}
+
+//CipherPercentByName returns the fraction of clients supporting the cipher with the given name
+func (m MappedTLSStatistics) CipherPercentByName(name string) (float64, bool) {
+	if m.cipherNames == nil {
+		for _, e := range m.Ciphers {
+			if e.Name == name {
+				return e.Percent, true
+			}
+		}
+		return 0, false
+	}
+	if id, present := m.cipherNames[name]; present {
+		return m.Ciphers[id].Percent, true
+	}
+	return 0, false
+}
+
+//ClientsRequiringLegacy returns the fraction of clients that only support protocols older than TLS 1.2
+func (m MappedTLSStatistics) ClientsRequiringLegacy() float64 {
+	return m.LegacyOnly
+}
+
+//DowngradeRisk returns the fraction of clients that support TLS 1.2 or newer but also SSL v3 or TLS 1.0,
+//i.e. those exposed to protocol downgrade attacks by servers that still allow the old protocols
+func (m MappedTLSStatistics) DowngradeRisk() float64 {
+	return m.Downgradable
+}
+
+//ProtocolPercent returns the fraction of clients supporting the protocol version, e.g. tls.VersionTLS12
+func (m MappedTLSStatistics) ProtocolPercent(version int) (float64, bool) {
+	e, present := m.Protocols[version]
+	return e.Percent, present
+}
+
+//SortedProtocolIDs returns the protocol IDs by descending support, then ascending ID, for a stable iteration order
+func (m MappedTLSStatistics) SortedProtocolIDs() []int {
+	return sortedIDs(m.Protocols)
+}
+
+//SortedCipherIDs returns the cipher IDs by descending support, then ascending ID, for a stable iteration order
+func (m MappedTLSStatistics) SortedCipherIDs() []int {
+	return sortedIDs(m.Ciphers)
+}
+
+//SortedCurveIDs returns the curve IDs by descending support, then ascending ID, for a stable iteration order
+func (m MappedTLSStatistics) SortedCurveIDs() []int {
+	return sortedIDs(m.Curves)
+}
+
+func sortedIDs(entries map[int]Entry) []int {
+	ids := make([]int, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		pi, pj := entries[ids[i]].Percent, entries[ids[j]].Percent
+		if pi != pj {
+			return pi > pj
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
 }
 
 //Entry TLS statistic entry
 type Entry struct {
-	ID      int
-	Percent float64
-	Name    string
+	ID      int     `json:"id"`
+	Percent float64 `json:"percent"` //fraction of clients supporting this entry, in [0,1] despite the name
+	Count   int64   `json:"count"`   //weighted number of clients supporting this entry
+	Name    string  `json:"name"`
+
+	OpenSSLName string `json:"openssl_name,omitempty"` //for ciphers with a known OpenSSL name
+
+	//security properties, see CipherStrength. Only Weak applies to protocols and curves
+	Bits           int  `json:"bits,omitempty"`
+	ForwardSecrecy bool `json:"forward_secrecy,omitempty"`
+	AEAD           bool `json:"aead,omitempty"`
+	Weak           bool `json:"weak,omitempty"`
+
+	Class CurveClass `json:"class,omitempty"` //classification of curves
+
+	//Cumulative is, for ciphers, the estimated share of clients supporting this cipher or one listed before it.
+	//Clients support many ciphers, so this is not the sum of the percentages: as in RecommendedCipherSuites, support
+	//is assumed independent and the union estimated as 1 - (1-p1)(1-p2)...
+	Cumulative float64 `json:"cumulative,omitempty"`
+}
+
+//AsFraction is the support of the entry as a fraction in [0,1], e.g. 0.95. It is the stored Percent
+func (e Entry) AsFraction() float64 {
+	return e.Percent
+}
+
+//AsPercent is the support of the entry as a percentage in [0,100], e.g. 95
+func (e Entry) AsPercent() float64 {
+	return 100 * e.Percent
+}
+
+//AsBasisPoints is the support of the entry in basis points in [0,10000], e.g. 9500
+func (e Entry) AsBasisPoints() float64 {
+	return 10000 * e.Percent
+}
+
+//setCumulative sets the cumulative coverage of the ordered entries
+func setCumulative(entries []Entry) {
+	uncovered := 1.0
+	for i := range entries {
+		uncovered *= 1 - entries[i].Percent
+		entries[i].Cumulative = 1 - uncovered
+	}
 }
 
 type intByInt64 struct {
@@ -63,36 +287,57 @@ func (s kv) Len() int {
 func (s kv) Swap(i, j int) {
 	s[i], s[j] = s[j], s[i]
 }
+
+//Less orders by descending count, then by ascending ID so that equal counts sort deterministically
 func (s kv) Less(i, j int) bool {
-	return s[i].v > s[j].v
+	if s[i].v != s[j].v {
+		return s[i].v > s[j].v
+	}
+	return s[i].k < s[j].k
 }
 
 //TLSStats contains statistics about TLS usage in the last year or so
 type TLSStats struct {
-	Protocols map[int]int64
-	Ciphers   map[int]int64
-	Curves    map[int]int64
-	Total     int64 //Total number of browsers/devices used in these stats
+	Protocols map[int]int64 `json:"protocols"`
+	Ciphers   map[int]int64 `json:"ciphers"`
+	Curves    map[int]int64 `json:"curves"`
+	Total     int64         `json:"total"` //Total number of browsers/devices used in these stats
+
+	LegacyOnly   int64         `json:"legacy_only"`  //number of browsers whose highest protocol is older than TLS 1.2
+	Downgradable int64         `json:"downgradable"` //number of browsers supporting TLS 1.2 or newer as well as TLS 1.0 or older
+	Highest      map[int]int64 `json:"highest"`      //number of browsers by their highest protocol
+
+	DeviceCounts map[string]int64 `json:"device_counts"` //number of browsers matched to each device profile, by device key
 
 	//internal data
 	devices   []Device
+	quality   QualityReport
 	protocols kv
 	ciphers   kv
 	curves    kv
 }
 
+//WriteJSON writes the raw counts in indented JSON form
+func (stats TLSStats) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(stats)
+}
+
 func (stats TLSStats) toJSONStruct(start, end time.Time) TLSStatistics {
 	stats.sort()
 	protocols := []Entry{}
 	for _, pp := range stats.protocols {
 		p := pp.k
 		v := pp.v
-		name := getProtocolName(p)
+		name := ProtocolName(p)
 		percent := float64(v) / float64(stats.Total)
 		protocols = append(protocols, Entry{
 			ID:      p,
 			Percent: percent,
+			Count:   v,
 			Name:    name,
+			Weak:    isWeakProtocol(p),
 		})
 	}
 
@@ -102,40 +347,71 @@ func (stats TLSStats) toJSONStruct(start, end time.Time) TLSStatistics {
 		c := cc.k
 		v := cc.v
 		percent := float64(v) / float64(stats.Total)
-		name := getCipherName(c, ciphersWithNonStandardNames)
+		name := CipherName(c, ciphersWithNonStandardNames)
+		strength, _ := GetCipherStrength(uint16(c))
 		ciphers = append(ciphers, Entry{
-			ID:      c,
-			Percent: percent,
-			Name:    name,
+			ID:             c,
+			Percent:        percent,
+			Count:          v,
+			Name:           name,
+			OpenSSLName:    OpenSSLCipherNames[uint16(c)],
+			Bits:           strength.Bits,
+			ForwardSecrecy: strength.ForwardSecrecy,
+			AEAD:           strength.AEAD,
+			Weak:           strength.Weak,
 		})
 
 	}
+	setCumulative(ciphers)
 	curves := []Entry{}
 
 	for _, cc := range stats.curves {
 		c := cc.k
 		v := cc.v
 		percent := float64(v) / float64(stats.Total)
-		name := getCurveName(c)
+		name := CurveName(c)
+		class := GetCurveClass(uint16(c))
 		curves = append(curves, Entry{
 			ID:      c,
 			Percent: percent,
+			Count:   v,
 			Name:    name,
+			Class:   class,
+			Weak:    class == CurveDiscouraged,
 		})
 	}
-	year, month, day := time.Now().Date()
+	legacyOnly, downgradable := 0.0, 0.0
+	var highest map[int]float64
+	if stats.Total > 0 {
+		legacyOnly = float64(stats.LegacyOnly) / float64(stats.Total)
+		downgradable = float64(stats.Downgradable) / float64(stats.Total)
+		for p, count := range stats.Highest {
+			if highest == nil {
+				highest = make(map[int]float64)
+			}
+			highest[p] = float64(count) / float64(stats.Total)
+		}
+	}
+	year, month, day := now().Date()
 	return TLSStatistics{
-		GenerationDate: time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
-		StartDate:      start,
-		EndDate:        end,
-		Protocols:      protocols,
-		Ciphers:        ciphers,
-		Curves:         curves,
+		SchemaVersion:    SchemaVersion,
+		GenerationDate:   time.Date(year, month, day, 0, 0, 0, 0, time.UTC),
+		StartDate:        start,
+		EndDate:          end,
+		Total:            stats.Total,
+		Protocols:        protocols,
+		Ciphers:          ciphers,
+		Curves:           curves,
+		Quality:          stats.quality,
+		LegacyOnly:       legacyOnly,
+		Downgradable:     downgradable,
+		HighestProtocols: highest,
+		DeviceCounts:     stats.DeviceCounts,
 	}
 }
 func (stats TLSStats) String() (out string) {
-	now := time.Now()
-	st := stats.toJSONStruct(now, now)
+	t := now()
+	st := stats.toJSONStruct(t, t)
 	out += fmt.Sprintf("Protocols\n=============\n")
 
 	for _, e := range st.Protocols {
@@ -144,7 +420,7 @@ func (stats TLSStats) String() (out string) {
 
 	out += fmt.Sprintf("Ciphers\n=============\n")
 	for _, e := range st.Ciphers {
-		out += fmt.Sprintf("\t%d\t%f\t%s\n", e.ID, e.Percent, e.Name)
+		out += fmt.Sprintf("\t%d\t%f\t%s%s\n", e.ID, e.Percent, e.Name, weakTag(e))
 	}
 
 	out += fmt.Sprintf("Curves\n=============\n")
@@ -155,6 +431,59 @@ func (stats TLSStats) String() (out string) {
 	return
 }
 
+//StringPercent is like String but renders the support as a right-aligned percentage, e.g. 95.00%
+func (stats TLSStats) StringPercent() string {
+	t := now()
+	var sb strings.Builder
+	stats.toJSONStruct(t, t).Print(&sb)
+	return sb.String()
+}
+
+//Print writes the protocols, ciphers and curves with their support as right-aligned percentages, tagging weak ones [WEAK]
+func (t TLSStatistics) Print(w io.Writer) error {
+	for _, c := range t.categories() {
+		if _, err := fmt.Fprintf(w, "%s\n=============\n", c.title); err != nil {
+			return err
+		}
+		if err := printEntries(w, c.entries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//PrintProtocols writes only the protocols, in the form of Print but without the heading
+func (t TLSStatistics) PrintProtocols(w io.Writer) error {
+	return printEntries(w, t.Protocols)
+}
+
+//PrintCiphers writes only the ciphers, in the form of Print but without the heading
+func (t TLSStatistics) PrintCiphers(w io.Writer) error {
+	return printEntries(w, t.Ciphers)
+}
+
+//PrintCurves writes only the curves, in the form of Print but without the heading
+func (t TLSStatistics) PrintCurves(w io.Writer) error {
+	return printEntries(w, t.Curves)
+}
+
+//weakTag marks weak protocols, ciphers and curves in printed output
+func weakTag(e Entry) string {
+	if e.Weak {
+		return " [WEAK]"
+	}
+	return ""
+}
+
+func printEntries(w io.Writer, entries []Entry) error {
+	for _, e := range entries {
+		if _, err := fmt.Fprintf(w, "\t%5d\t%7.2f%%\t%s%s\n", e.ID, 100*e.Percent, e.Name, weakTag(e)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (stats *TLSStats) sort() {
 	data := kv{}
 	for k, v := range stats.Protocols {
@@ -170,13 +499,6 @@ func (stats *TLSStats) sort() {
 	sort.Sort(data)
 	stats.ciphers = data
 
-	data = kv{}
-	for k, v := range stats.Ciphers {
-		data = append(data, intByInt64{k, v})
-	}
-	sort.Sort(data)
-	stats.ciphers = data
-
 	data = kv{}
 	for k, v := range stats.Curves {
 		data = append(data, intByInt64{k, v})
@@ -186,11 +508,13 @@ func (stats *TLSStats) sort() {
 
 }
 
+//cipherMaps maps the cipher suites of the devices to the names the devices give them. Devices from a DeviceSource
+//need not name their suites
 func cipherMaps(devices []Device) map[int]string {
 	out := make(map[int]string)
 	for _, dev := range devices {
 		for ind, id := range dev.SuiteIds {
-			if _, present := out[id]; !present {
+			if _, present := out[id]; !present && ind < len(dev.SuiteNames) {
 				out[id] = dev.SuiteNames[ind]
 			}
 		}
@@ -198,8 +522,36 @@ func cipherMaps(devices []Device) map[int]string {
 	return out
 }
 
-func getProtocolName(p int) string {
+//UnknownCiphers returns the IDs of the cipher suites the devices support that are not in CipherSuiteMap, mapped to
+//the name SSL Labs gives them, if any. Those without a name are reported as "Nonstandard Cipher". GREASE values are
+//not ciphers and are left out. The IDs can be named with RegisterCipherName, and added to CipherSuiteMap upstream
+func UnknownCiphers(devices []Device) map[int]string {
+	unknown := make(map[int]string)
+	for _, dev := range devices {
+		for ind, id := range dev.SuiteIds {
+			if isGREASE(id) {
+				continue
+			}
+			if _, present := LookupCipherName(uint16(id)); present {
+				continue
+			}
+			name := ""
+			if ind < len(dev.SuiteNames) {
+				name = dev.SuiteNames[ind]
+			}
+			if unknown[id] == "" {
+				unknown[id] = name
+			}
+		}
+	}
+	return unknown
+}
+
+//ProtocolName returns the name of a protocol version, e.g. "TLS v1.2" for tls.VersionTLS12, as used in the entries
+func ProtocolName(p int) string {
 	switch p {
+	case VersionSSL20:
+		return "SSL v2.0"
 	case tls.VersionSSL30:
 		return "SSL v3.0"
 	case tls.VersionTLS10:
@@ -215,8 +567,55 @@ func getProtocolName(p int) string {
 	}
 }
 
-func getCipherName(c int, nonStandard map[int]string) string {
-	if cipher, present := CipherSuiteMap[uint16(c)]; present {
+//namesMutex guards CipherSuiteMap, NamedCurves and the cipher strengths derived from them
+var namesMutex sync.RWMutex
+
+//RegisterCipherName adds, or replaces, the IANA name of a cipher suite, e.g. one that CipherSuiteMap lacks.
+//Its strength is derived from the name. Safe for concurrent use, unlike modifying CipherSuiteMap directly
+func RegisterCipherName(id uint16, name string) {
+	namesMutex.Lock()
+	defer namesMutex.Unlock()
+	CipherSuiteMap[id] = name
+	cipherStrengths[id] = cipherStrengthFromName(name)
+}
+
+//RegisterCurveName adds, or replaces, the name of a named curve, e.g. one that NamedCurves lacks.
+//Safe for concurrent use, unlike modifying NamedCurves directly
+func RegisterCurveName(id uint16, name string) {
+	namesMutex.Lock()
+	defer namesMutex.Unlock()
+	NamedCurves[id] = name
+}
+
+//LookupCipherName returns the IANA name of a cipher suite, if known
+func LookupCipherName(id uint16) (string, bool) {
+	namesMutex.RLock()
+	defer namesMutex.RUnlock()
+	name, present := CipherSuiteMap[id]
+	return name, present
+}
+
+//LookupCurveName returns the name of a named curve, if known
+func LookupCurveName(id uint16) (string, bool) {
+	namesMutex.RLock()
+	defer namesMutex.RUnlock()
+	name, present := NamedCurves[id]
+	return name, present
+}
+
+//CipherNames returns the IANA and OpenSSL names of a cipher suite. Either is empty if not known
+func CipherNames(c int) (iana, openssl string) {
+	iana, _ = LookupCipherName(uint16(c))
+	return iana, OpenSSLCipherNames[uint16(c)]
+}
+
+//CipherName returns the name of a cipher suite as used in the entries: its IANA name if known, or else its name in
+//nonStandard, e.g. the names SSL Labs gives to the device suites (see UnknownCiphers). nonStandard may be nil
+func CipherName(c int, nonStandard map[int]string) string {
+	if isGREASE(c) {
+		return "GREASE"
+	}
+	if cipher, present := LookupCipherName(uint16(c)); present {
 		return cipher
 	} else if cipher, present := nonStandard[c]; present {
 		return cipher
@@ -224,8 +623,18 @@ func getCipherName(c int, nonStandard map[int]string) string {
 	return "Nonstandard Cipher"
 }
 
-func getCurveName(c int) string {
-	if curve, present := NamedCurves[uint16(c)]; present {
+//isGREASE reports whether id is one of the reserved 0x?A?A values clients advertise to keep servers tolerant
+//of unknown values, see RFC 8701. They are not real ciphers or curves
+func isGREASE(id int) bool {
+	return id&0x0f0f == 0x0a0a && id>>8 == id&0xff
+}
+
+//CurveName returns the name of a named curve as used in the entries
+func CurveName(c int) string {
+	if isGREASE(c) {
+		return "GREASE"
+	}
+	if curve, present := LookupCurveName(uint16(c)); present {
 		return curve
 	}
 	return "Nonstandard Curve"
@@ -0,0 +1,19 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEqualCountsSortByID(t *testing.T) {
+	ciphers := map[int]int64{0x2f: 5, 0x35: 5, 0x0a: 5, 0xc02f: 9, 0x9c: 1, 0x05: 5}
+	want := kv{{0xc02f, 9}, {0x05, 5}, {0x0a, 5}, {0x2f, 5}, {0x35, 5}, {0x9c, 1}}
+	//map iteration order varies, so sort several times
+	for i := 0; i < 20; i++ {
+		stats := TLSStats{Ciphers: ciphers}
+		stats.sort()
+		if !reflect.DeepEqual(stats.ciphers, want) {
+			t.Fatalf("sorted ciphers = %v, want %v", stats.ciphers, want)
+		}
+	}
+}
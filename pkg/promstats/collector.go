@@ -0,0 +1,84 @@
+//go:build prometheus
+// +build prometheus
+
+//Package promstats exports tls-stats statistics as Prometheus metrics.
+//It lives apart from the stats package, behind the prometheus build tag, so that the core remains free of the
+//Prometheus dependency. Build with -tags prometheus to include it
+package promstats
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	stats "github.com/adedayo/tls-stats/pkg"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	protocolDesc = prometheus.NewDesc("tls_stats_protocol_support", "Fraction of clients supporting a TLS protocol version",
+		[]string{"id", "protocol"}, nil)
+	cipherDesc = prometheus.NewDesc("tls_stats_cipher_support", "Fraction of clients supporting a TLS cipher suite",
+		[]string{"id", "cipher"}, nil)
+	curveDesc = prometheus.NewDesc("tls_stats_curve_support", "Fraction of clients supporting a named curve",
+		[]string{"id", "curve"}, nil)
+)
+
+//Collector is a prometheus.Collector reporting the protocol, cipher and curve support in a statistics file.
+//The file is re-read at most once per refresh interval
+type Collector struct {
+	path     string
+	interval time.Duration
+
+	mu         sync.Mutex
+	statistics stats.TLSStatistics
+	loaded     time.Time
+}
+
+//NewCollector creates a Collector for the statistics at path, e.g. stats.CurrentStatsFile(),
+//reloading them once they are older than interval
+func NewCollector(path string, interval time.Duration) *Collector {
+	return &Collector{
+		path:     path,
+		interval: interval,
+	}
+}
+
+//Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- protocolDesc
+	ch <- cipherDesc
+	ch <- curveDesc
+}
+
+//Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	statistics, err := c.current()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(protocolDesc, err)
+		return
+	}
+	collect(ch, protocolDesc, statistics.Protocols)
+	collect(ch, cipherDesc, statistics.Ciphers)
+	collect(ch, curveDesc, statistics.Curves)
+}
+
+func (c *Collector) current() (stats.TLSStatistics, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loaded.IsZero() || time.Since(c.loaded) > c.interval {
+		statistics, err := stats.LoadStatistics(c.path)
+		if err != nil {
+			return statistics, err
+		}
+		c.statistics = statistics
+		c.loaded = time.Now()
+	}
+	return c.statistics, nil
+}
+
+func collect(ch chan<- prometheus.Metric, desc *prometheus.Desc, entries []stats.Entry) {
+	for _, e := range entries {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, e.Percent, strconv.Itoa(e.ID), e.Name)
+	}
+}
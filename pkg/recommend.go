@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+//RecommendedCipherSuites returns the IDs of the non-weak cipher suites supported by Go's crypto/tls, ordered by descending
+//client support and trimmed to the smallest prefix that covers minCoverage (a fraction in [0,1]) of clients.
+//The result can be used as tls.Config.CipherSuites. TLS 1.3 suites are not configurable in Go, so they are left out.
+//
+//Coverage is estimated from the per-cipher percentages as if clients supported the ciphers independently,
+//i.e. a set of ciphers covers 1 - (1-p1)(1-p2)... of clients
+func (t TLSStatistics) RecommendedCipherSuites(minCoverage float64) []uint16 {
+	supported := make(map[uint16]bool)
+	for _, c := range tls.CipherSuites() {
+		tls12 := false
+		for _, v := range c.SupportedVersions {
+			if v != tls.VersionTLS13 {
+				tls12 = true
+			}
+		}
+		supported[c.ID] = tls12
+	}
+
+	return t.coveringCiphers(minCoverage, func(id uint16) bool { return supported[id] })
+}
+
+//coveringCiphers returns the non-weak ciphers accepted by include, ordered by descending client support and trimmed to
+//the smallest prefix that covers minCoverage of clients
+func (t TLSStatistics) coveringCiphers(minCoverage float64, include func(id uint16) bool) []uint16 {
+	suites := []uint16{}
+	percents := []float64{}
+	for _, e := range t.Ciphers {
+		id := uint16(e.ID)
+		if strength, _ := GetCipherStrength(id); !include(id) || strength.Weak {
+			continue
+		}
+		suites = append(suites, id)
+		percents = append(percents, e.Percent)
+		if independentCoverage(percents) >= minCoverage {
+			break
+		}
+	}
+	return suites
+}
+
+//NginxCipherString returns a value for nginx's ssl_ciphers directive: the OpenSSL names of the non-weak ciphers, ordered
+//and trimmed as in RecommendedCipherSuites and joined with ':'. Ciphers without an entry in OpenSSLCipherNames are left
+//out, as are TLS 1.3 suites, which nginx does not configure through ssl_ciphers
+func (t TLSStatistics) NginxCipherString(minCoverage float64) string {
+	names := []string{}
+	for _, id := range t.coveringCiphers(minCoverage, func(id uint16) bool {
+		_, present := OpenSSLCipherNames[id]
+		return present && id>>8 != 0x13
+	}) {
+		names = append(names, OpenSSLCipherNames[id])
+	}
+	return strings.Join(names, ":")
+}
+
+//nginxProtocolNames are the names nginx's ssl_protocols directive uses for the protocol versions
+var nginxProtocolNames = map[int]string{
+	tls.VersionSSL30: "SSLv3",
+	tls.VersionTLS10: "TLSv1",
+	tls.VersionTLS11: "TLSv1.1",
+	tls.VersionTLS12: "TLSv1.2",
+	tls.VersionTLS13: "TLSv1.3",
+}
+
+//NginxProtocols returns a value for nginx's ssl_protocols directive. It lists TLS 1.2 and 1.3 and, only if these cover
+//less than minCoverage of clients, the older TLS versions needed to reach it. SSL is never enabled
+func (t TLSStatistics) NginxProtocols(minCoverage float64) string {
+	return strings.Join(t.protocolNames(minCoverage, nginxProtocolNames), " ")
+}
+
+//protocolNames lists the names of the protocols to enable, oldest first, as described in NginxProtocols
+func (t TLSStatistics) protocolNames(minCoverage float64, names map[int]string) []string {
+	percents := make(map[int]float64)
+	for _, e := range t.Protocols {
+		percents[e.ID] = e.Percent
+	}
+	enabled := []string{}
+	coverage := 0.0
+	for i := len(protocolVersions) - 1; i >= 0; i-- {
+		version := protocolVersions[i]
+		name, known := names[version]
+		if !known || version < tls.VersionTLS10 {
+			continue
+		}
+		if isWeakProtocol(version) && coverage >= minCoverage {
+			break
+		}
+		if percents[version] > 0 || !isWeakProtocol(version) {
+			enabled = append(enabled, name)
+		}
+		if percents[version] > coverage {
+			coverage = percents[version]
+		}
+	}
+	//nginx lists protocols oldest first
+	for i, j := 0, len(enabled)-1; i < j; i, j = i+1, j-1 {
+		enabled[i], enabled[j] = enabled[j], enabled[i]
+	}
+	return enabled
+}
+
+//NginxConfig returns the ssl_protocols and ssl_ciphers directives for an nginx server block, see NginxProtocols and NginxCipherString
+func (t TLSStatistics) NginxConfig(minCoverage float64) string {
+	return fmt.Sprintf("ssl_protocols %s;\nssl_ciphers '%s';\nssl_prefer_server_ciphers on;\n",
+		t.NginxProtocols(minCoverage), t.NginxCipherString(minCoverage))
+}
+
+//EstimateCoverage estimates the fraction of clients able to connect to a server offering the given protocols and ciphers.
+//A client needs both a shared protocol and a shared cipher. Since protocol support is a contiguous range, the protocol
+//coverage is taken as that of the most supported offered protocol; cipher coverage is estimated as in
+//RecommendedCipherSuites, and the two are assumed independent
+func (m MappedTLSStatistics) EstimateCoverage(protocols []int, ciphers []int) float64 {
+	protocolCoverage := 0.0
+	for _, p := range protocols {
+		if e, present := m.Protocols[p]; present && e.Percent > protocolCoverage {
+			protocolCoverage = e.Percent
+		}
+	}
+	percents := []float64{}
+	for _, c := range ciphers {
+		if e, present := m.Ciphers[c]; present {
+			percents = append(percents, e.Percent)
+		}
+	}
+	return protocolCoverage * independentCoverage(percents)
+}
+
+//independentCoverage is the share of clients supporting at least one of the items, assuming independent support
+func independentCoverage(percents []float64) float64 {
+	uncovered := 1.0
+	for _, p := range percents {
+		uncovered *= 1 - p
+	}
+	return 1 - uncovered
+}
+
+//goProtocolNames and goCurveNames are the crypto/tls constant names of the protocols and curves
+var (
+	goProtocolNames = map[int]string{
+		tls.VersionTLS10: "tls.VersionTLS10",
+		tls.VersionTLS11: "tls.VersionTLS11",
+		tls.VersionTLS12: "tls.VersionTLS12",
+		tls.VersionTLS13: "tls.VersionTLS13",
+	}
+	goCurveNames = map[int]string{
+		23: "tls.CurveP256",
+		24: "tls.CurveP384",
+		25: "tls.CurveP521",
+		29: "tls.X25519",
+	}
+)
+
+//GoTLSConfigSnippet returns Go source for a tls.Config literal setting MinVersion, CipherSuites and CurvePreferences.
+//MinVersion is the oldest protocol NginxProtocols would enable and CipherSuites are the RecommendedCipherSuites.
+//CurvePreferences lists the curves crypto/tls supports by descending client support, leaving out discouraged ones;
+//other supported curves that crypto/tls does not implement are listed as comments
+func (t TLSStatistics) GoTLSConfigSnippet(minCoverage float64) string {
+	var b strings.Builder
+	b.WriteString("&tls.Config{\n")
+	if protocols := t.protocolNames(minCoverage, goProtocolNames); len(protocols) > 0 {
+		fmt.Fprintf(&b, "\tMinVersion: %s,\n", protocols[0])
+	}
+	b.WriteString("\tCipherSuites: []uint16{\n")
+	for _, id := range t.RecommendedCipherSuites(minCoverage) {
+		fmt.Fprintf(&b, "\t\ttls.%s,\n", tls.CipherSuiteName(id))
+	}
+	b.WriteString("\t},\n\tCurvePreferences: []tls.CurveID{\n")
+	for _, e := range t.Curves {
+		if e.Percent == 0 || GetCurveClass(uint16(e.ID)) == CurveDiscouraged {
+			continue
+		}
+		if name, present := goCurveNames[e.ID]; present {
+			fmt.Fprintf(&b, "\t\t%s,\n", name)
+		} else {
+			fmt.Fprintf(&b, "\t\t//%s is not supported by crypto/tls\n", e.Name)
+		}
+	}
+	b.WriteString("\t},\n}\n")
+	return b.String()
+}
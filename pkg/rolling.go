@@ -0,0 +1,141 @@
+package stats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+//rollingBrowserStats is the rolling browser data, see UpdateRollingBrowserStats
+var rollingBrowserStats = path.Join(dataHome, "browser-stats-rolling.tsv")
+
+//rollingHeader is the header row of the rolling browser data. Its columns are those of DefaultBrowserColumns
+const rollingHeader = "date\tos_family\tos_major\tbrowser_family\tbrowser_major\tview_count"
+
+//RollingBrowserStatsFile is the path of the rolling browser data, see UpdateRollingBrowserStats
+func RollingBrowserStatsFile() string {
+	return rollingBrowserStats
+}
+
+//RollingUpdate reports the rows added to and evicted from the rolling browser data
+type RollingUpdate struct {
+	Rows       int //rows in the rolling data after the update
+	Added      int //new rows
+	Duplicates int //rows already in the rolling data, which were ignored
+	Evicted    int //rows that fell out of the lookback window
+}
+
+func (u RollingUpdate) String() string {
+	return fmt.Sprintf("%d rows added, %d duplicates ignored and %d rows evicted, leaving %d rows",
+		u.Added, u.Duplicates, u.Evicted, u.Rows)
+}
+
+//UpdateRollingBrowserStats merges browser data, in any form LoadBrowserStatsReader reads, into the rolling browser
+//data at RollingBrowserStatsFile. Rows are identified by their date, OS and browser: those already present are
+//ignored, so the daily download can be merged in repeatedly and only its new dates are added. Rows older than
+//lookback from the most recent date are evicted, so the rolling data only ever holds the window being analysed.
+//Nothing is evicted if lookback is not positive.
+//
+//The rolling data is a TSV file with a header row, sorted by date, whose columns are date (formatted as 2006-01-02),
+//os_family, os_major, browser_family, browser_major and view_count. It can be analysed like the Wikipedia data,
+//see WithRollingData
+func UpdateRollingBrowserStats(r io.Reader, lookback time.Duration) (RollingUpdate, error) {
+	return updateRolling(rollingBrowserStats, r, Config{Lookback: lookback})
+}
+
+//rollingKey identifies a row of browser data
+type rollingKey struct {
+	date                                                  time.Time
+	osFamily, osMajor, browserFamily, browserMajorVersion string
+}
+
+func keyOf(b Browser) rollingKey {
+	return rollingKey{b.Date, b.OSFamily, b.OSMajorVersion, b.BrowserFamily, b.BrowserMajorVersion}
+}
+
+//updateRolling merges the browser data read with the config into the rolling data in file
+func updateRolling(file string, r io.Reader, config Config) (update RollingUpdate, err error) {
+	rows := []Browser{}
+	if f, e := os.Open(file); e == nil {
+		rows, _, err = loadBrowserStats(f, Config{Lookback: config.Lookback})
+		f.Close()
+		if err != nil {
+			return update, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	incoming, _, err := loadBrowserStats(r, config)
+	if err != nil {
+		return
+	}
+	present := make(map[rollingKey]bool, len(rows))
+	for _, b := range rows {
+		present[keyOf(b)] = true
+	}
+	for _, b := range incoming {
+		if b.Date.IsZero() {
+			return update, errors.New("aggregated browser data has no dates to add to the rolling data")
+		}
+		key := keyOf(b)
+		if present[key] {
+			update.Duplicates++
+			continue
+		}
+		present[key] = true
+		rows = append(rows, b)
+		update.Added++
+	}
+
+	var end time.Time
+	for _, b := range rows {
+		if b.Date.After(end) {
+			end = b.Date
+		}
+	}
+	cutoff := end.Add(-config.Lookback)
+	kept := rows[:0]
+	for _, b := range rows {
+		if config.Lookback <= 0 || b.Date.After(cutoff) {
+			kept = append(kept, b)
+		} else {
+			update.Evicted++
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].Date.Before(kept[j].Date)
+	})
+	update.Rows = len(kept)
+
+	err = writeFile(file, func(w io.Writer) error {
+		out := bufio.NewWriter(w)
+		fmt.Fprintln(out, rollingHeader)
+		for _, b := range kept {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%d\n", b.Date.Format(dateFormat), b.OSFamily, b.OSMajorVersion,
+				b.BrowserFamily, b.BrowserMajorVersion, b.Count)
+		}
+		return out.Flush()
+	})
+	return
+}
+
+//updateRollingData merges the most recently downloaded browser data into the rolling data
+func (config Config) updateRollingData() error {
+	file := freshestDataFile(browserStatsData(), browserStatsFile)
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	//the date range applies to the analysis, not to what is kept
+	config.StartDate, config.EndDate = time.Time{}, time.Time{}
+	update, err := updateRolling(rollingBrowserStats, f, config)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	logf("Updated %s from %s: %s", rollingBrowserStats, file, update)
+	return nil
+}
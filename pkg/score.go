@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"crypto/tls"
+	"fmt"
+	"math"
+)
+
+//ScoreSummary is a single client security posture score along with the components it is computed from.
+//All components are fractions of clients in [0,1]
+type ScoreSummary struct {
+	Score            float64 //0-100, higher is better
+	Grade            string  //A to F
+	TLS10OrOlderOnly float64 //clients that cannot negotiate anything newer than TLS 1.0, unlike TLSStatistics.LegacyOnly
+	TLS12Support     float64 //clients supporting TLS 1.2
+	TLS13Support     float64 //clients supporting TLS 1.3
+	StrongCipher     float64 //clients supporting at least one forward secret AEAD cipher
+	WeakCiphersOnly  float64 //clients offering no cipher that isn't weak
+}
+
+//Weights of the components of the security score; they add up to 100
+const (
+	legacyWeight       = 30
+	tls13Weight        = 20
+	strongCipherWeight = 25
+	weakCipherWeight   = 25
+)
+
+//SecurityScore rolls the protocol and cipher support into a single score. Since each Percent is the support of a single
+//item, the share of clients supporting any of several items is estimated by the most supported of them, which is a lower bound.
+//The clients supporting nothing newer than TLS 1.0 are counted exactly from the HighestProtocols, if any
+func (t TLSStatistics) SecurityScore() (s ScoreSummary) {
+	m := t.ToMapped()
+	s.TLS12Support = m.Protocols[tls.VersionTLS12].Percent
+	s.TLS13Support = m.Protocols[tls.VersionTLS13].Percent
+	modern := 0.0
+	if len(t.HighestProtocols) > 0 {
+		for p, fraction := range t.HighestProtocols {
+			if p > tls.VersionTLS10 {
+				modern += fraction
+			}
+		}
+	} else {
+		for _, p := range []int{tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13} {
+			if pc := m.Protocols[p].Percent; pc > modern {
+				modern = pc
+			}
+		}
+	}
+	//the fractions may add up to slightly more than 1
+	s.TLS10OrOlderOnly = math.Max(0, 1-modern)
+
+	notWeak := 0.0
+	for _, e := range t.Ciphers {
+		strength, known := GetCipherStrength(uint16(e.ID))
+		if !known {
+			continue
+		}
+		if !strength.Weak && e.Percent > notWeak {
+			notWeak = e.Percent
+		}
+		if strength.ForwardSecrecy && strength.AEAD && e.Percent > s.StrongCipher {
+			s.StrongCipher = e.Percent
+		}
+	}
+	s.WeakCiphersOnly = 1 - notWeak
+
+	s.Score = legacyWeight*(1-s.TLS10OrOlderOnly) + tls13Weight*s.TLS13Support + strongCipherWeight*s.StrongCipher +
+		weakCipherWeight*(1-s.WeakCiphersOnly)
+	s.Grade = grade(s.Score)
+	return
+}
+
+func grade(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	case score >= 50:
+		return "E"
+	default:
+		return "F"
+	}
+}
+
+func (s ScoreSummary) String() string {
+	return fmt.Sprintf("Score: %.1f (%s)\n\tTLS 1.0 or older only: %.2f%%\n\tTLS 1.2 support: %.2f%%\n\tTLS 1.3 support: %.2f%%\n"+
+		"\tForward secret AEAD cipher: %.2f%%\n\tWeak ciphers only: %.2f%%\n", s.Score, s.Grade, 100*s.TLS10OrOlderOnly,
+		100*s.TLS12Support, 100*s.TLS13Support, 100*s.StrongCipher, 100*s.WeakCiphersOnly)
+}
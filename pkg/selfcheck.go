@@ -0,0 +1,149 @@
+package stats
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+//CheckResult is the outcome of SelfCheck
+type CheckResult struct {
+	Steps []CheckStep
+}
+
+//CheckStep is the outcome of one of the checks of SelfCheck
+type CheckStep struct {
+	Name   string
+	OK     bool
+	Detail string //what was found, or why the check failed
+}
+
+//OK reports whether all the checks passed
+func (c CheckResult) OK() bool {
+	for _, s := range c.Steps {
+		if !s.OK {
+			return false
+		}
+	}
+	return true
+}
+
+func (c CheckResult) String() string {
+	var sb strings.Builder
+	for _, s := range c.Steps {
+		status := "ok"
+		if !s.OK {
+			status = "FAILED"
+		}
+		fmt.Fprintf(&sb, "%-6s\t%s: %s\n", status, s.Name, s.Detail)
+	}
+	return sb.String()
+}
+
+//SelfCheck verifies the data pipeline without changing any files: that the data directories are writable, the data
+//sources are reachable, the most recently downloaded data parses, and the current statistics are neither empty nor stale
+//by DefaultStaleness. An error listing the failed checks is returned if any fail
+func SelfCheck() (result CheckResult, err error) {
+	add := func(name, detail string, e error) {
+		step := CheckStep{Name: name, OK: e == nil, Detail: detail}
+		if e != nil {
+			step.Detail = e.Error()
+		}
+		result.Steps = append(result.Steps, step)
+	}
+
+	for _, dir := range []string{statsHome, dataHome} {
+		add("directory "+dir, "writable", checkWritableDir(dir))
+	}
+
+	add("reachable "+BrowserStats, "reachable", checkReachable(BrowserStats))
+	deviceURL, e := deviceDetailsURL()
+	if e == nil {
+		e = checkReachable(deviceURL)
+	}
+	add("reachable "+deviceURL, "reachable", e)
+
+	add(checkData())
+	add(checkCurrentStats())
+
+	failed := []string{}
+	for _, s := range result.Steps {
+		if !s.OK {
+			failed = append(failed, s.Name)
+		}
+	}
+	if len(failed) > 0 {
+		err = fmt.Errorf("self-check failed: %s", strings.Join(failed, ", "))
+	}
+	return
+}
+
+//checkWritableDir checks, by its permissions, that dir is a directory its owner can write to
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if info.Mode().Perm()&0200 == 0 {
+		return fmt.Errorf("%s is not writable", dir)
+	}
+	return nil
+}
+
+func checkReachable(u string) error {
+	resp, err := http.Head(u)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	return nil
+}
+
+//checkData parses the most recently downloaded data
+func checkData() (name, detail string, err error) {
+	name = "downloaded data"
+	dates, err := DataDates()
+	if err != nil {
+		return
+	}
+	if len(dates) == 0 {
+		return name, "", fmt.Errorf("%w: no data has been downloaded to %s", ErrNoData, dataHome)
+	}
+	date := dates[len(dates)-1].Format(dateFormat)
+	f, err := os.Open(browserStatsFile(date))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	browsers, summary, err := LoadBrowserStatsReader(f, DefaultLookback)
+	if err != nil {
+		return
+	}
+	devices, err := SSLLabsDeviceFile(deviceCiphersFile(date)).Load()
+	if err != nil {
+		return
+	}
+	return name, fmt.Sprintf("data of %s has %d browser records (%s) and %d devices", date, len(browsers), summary, len(devices)), nil
+}
+
+func checkCurrentStats() (name, detail string, err error) {
+	name = "current statistics"
+	statistics, err := LoadStatistics(currentStatsFile())
+	if err != nil {
+		return
+	}
+	if statistics.Total == 0 || len(statistics.Protocols) == 0 || len(statistics.Ciphers) == 0 {
+		return name, "", fmt.Errorf("the statistics generated on %s are empty", statistics.GenerationDate.Format(dateFormat))
+	}
+	if statistics.GenerationDate.Before(now().Add(-DefaultStaleness)) {
+		return name, "", fmt.Errorf("the statistics generated on %s are stale", statistics.GenerationDate.Format(dateFormat))
+	}
+	return name, fmt.Sprintf("generated on %s", statistics.GenerationDate.Format(dateFormat)), nil
+}
@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+//StatsHandler serves the current statistics as JSON on /stats, their mapped form on /stats/mapped,
+//and recomputes them from freshly downloaded data on a POST to /refresh
+func StatsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		statistics, err := GetStats(false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, "public, max-age=3600", statistics)
+	})
+	mux.HandleFunc("/stats/mapped", func(w http.ResponseWriter, r *http.Request) {
+		statistics, err := GetStats(false)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, "public, max-age=3600", statistics.ToMapped())
+	})
+	mux.HandleFunc("/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "use POST to refresh the statistics", http.StatusMethodNotAllowed)
+			return
+		}
+		statistics, err := GetStats(true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, "no-store", statistics)
+	})
+	return mux
+}
+
+//Serve listens on addr and serves the statistics using StatsHandler
+func Serve(addr string) error {
+	return http.ListenAndServe(addr, StatsHandler())
+}
+
+func writeJSON(w http.ResponseWriter, cacheControl string, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", cacheControl)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
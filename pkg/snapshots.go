@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//SnapshotInfo describes a backup of previously generated statistics
+type SnapshotInfo struct {
+	Date           time.Time //date in the file name
+	GenerationDate time.Time //date the statistics were generated, from the file contents
+	Path           string
+}
+
+//ListSnapshots lists the backups of the statistics, tls-stats-<date>.json or .json.gz, newest first.
+//The current statistics are not included. Files whose name has no valid date or that cannot be read are skipped
+func ListSnapshots() (snapshots []SnapshotInfo, err error) {
+	files, err := filepath.Glob(path.Join(statsHome, "tls-stats-*.json*"))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), compressedSuffix)
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		date, e := time.Parse(dateFormat, strings.TrimSuffix(strings.TrimPrefix(name, "tls-stats-"), ".json"))
+		if e != nil {
+			continue
+		}
+		statistics, e := LoadStatistics(file)
+		if e != nil {
+			logf("Skipping snapshot %s: %v", file, e)
+			continue
+		}
+		snapshots = append(snapshots, SnapshotInfo{
+			Date:           date,
+			GenerationDate: statistics.GenerationDate,
+			Path:           file,
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Date.After(snapshots[j].Date)
+	})
+	return
+}
+
+//TrendPoint is the support of an entry in one snapshot
+type TrendPoint struct {
+	Date    time.Time //generation date of the snapshot
+	Percent float64
+	Present bool //whether the snapshot has the entry; if not, Percent is 0
+}
+
+//Trend is the support of the protocol, cipher or curve with the given ID over the backups listed by ListSnapshots and
+//the current statistics, oldest first. Snapshots lacking the entry are included with a Percent of 0 and Present unset
+func Trend(id int, c Category) ([]TrendPoint, error) {
+	switch c {
+	case ProtocolCategory, CipherCategory, CurveCategory:
+	default:
+		return nil, fmt.Errorf("unknown category %q", c)
+	}
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		files = append(files, snapshots[i].Path)
+	}
+	if current := currentStatsFile(); fileExists(current) {
+		files = append(files, current)
+	}
+
+	points := []TrendPoint{}
+	for _, file := range files {
+		statistics, err := LoadStatistics(file)
+		if err != nil {
+			return nil, err
+		}
+		point := TrendPoint{Date: statistics.GenerationDate}
+		for _, e := range statistics.Entries(c) {
+			if e.ID == id {
+				point.Percent = e.Percent
+				point.Present = true
+				break
+			}
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
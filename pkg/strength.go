@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"crypto/tls"
+	"strings"
+)
+
+//CipherStrength describes the security properties of a cipher suite
+type CipherStrength struct {
+	Bits           int  //effective symmetric key size
+	ForwardSecrecy bool //ephemeral key exchange
+	AEAD           bool //authenticated encryption, e.g. GCM, CCM or ChaCha20-Poly1305
+	Weak           bool //uses a broken or deprecated primitive, or a key smaller than 128 bits
+}
+
+//cipherStrengths is derived from the IANA names in CipherSuiteMap
+var cipherStrengths = make(map[uint16]CipherStrength)
+
+func init() {
+	for id, name := range CipherSuiteMap {
+		cipherStrengths[id] = cipherStrengthFromName(name)
+	}
+}
+
+//GetCipherStrength returns the security properties of a cipher suite, if it is a known IANA cipher suite
+func GetCipherStrength(id uint16) (CipherStrength, bool) {
+	namesMutex.RLock()
+	defer namesMutex.RUnlock()
+	s, present := cipherStrengths[id]
+	return s, present
+}
+
+func cipherStrengthFromName(name string) (s CipherStrength) {
+	if strings.HasSuffix(name, "_SCSV") {
+		//signalling values, not actual cipher suites
+		return
+	}
+	kx, bulk := name, name
+	if parts := strings.SplitN(name, "_WITH_", 2); len(parts) == 2 {
+		kx, bulk = parts[0], parts[1]
+	} else {
+		//TLS 1.3 suites have no key exchange in their name and are always ephemeral
+		s.ForwardSecrecy = true
+	}
+	if strings.Contains(kx, "DHE_") || strings.HasSuffix(kx, "DHE") {
+		s.ForwardSecrecy = true
+	}
+
+	switch {
+	case strings.HasPrefix(bulk, "NULL"):
+		s.Bits = 0
+	case strings.Contains(bulk, "_40_") || strings.Contains(bulk, "DES40"):
+		s.Bits = 40
+	case strings.Contains(bulk, "3DES"):
+		s.Bits = 112
+	case strings.Contains(bulk, "DES"):
+		s.Bits = 56
+	case strings.Contains(bulk, "_256") || strings.Contains(bulk, "CHACHA20") || strings.Contains(bulk, "GOST"):
+		s.Bits = 256
+	default:
+		s.Bits = 128
+	}
+
+	for _, aead := range []string{"GCM", "CCM", "POLY1305", "MGM"} {
+		if strings.Contains(bulk, aead) {
+			s.AEAD = true
+		}
+	}
+	s.Weak = s.Bits < 128 || isWeakCipherName(name)
+	return
+}
+
+//isWeakCipherName reports whether the IANA name of a cipher suite shows it uses a broken or deprecated primitive
+func isWeakCipherName(name string) bool {
+	for _, weak := range []string{"NULL", "EXPORT", "anon", "RC4", "RC2", "DES", "IDEA", "MD5"} {
+		if strings.Contains(name, weak) {
+			return true
+		}
+	}
+	return false
+}
+
+//isWeakProtocol reports whether a protocol version is deprecated, i.e. older than TLS 1.2
+func isWeakProtocol(version int) bool {
+	return version < tls.VersionTLS12
+}
+
+//CurveClass classifies named groups by how advisable they are to use
+type CurveClass string
+
+//Curve classes
+const (
+	CurveRecommended CurveClass = "recommended" //modern, widely supported groups
+	CurveLegacy      CurveClass = "legacy"      //still secure, but not preferred
+	CurveDiscouraged CurveClass = "discouraged" //small, binary field, or arbitrary explicit curves
+)
+
+//curveClasses is keyed by the same IDs as NamedCurves
+var curveClasses = map[uint16]CurveClass{
+	23:  CurveRecommended, //secp256r1
+	24:  CurveRecommended, //secp384r1
+	29:  CurveRecommended, //x25519
+	25:  CurveLegacy,      //secp521r1
+	26:  CurveLegacy,      //brainpoolP256r1
+	27:  CurveLegacy,      //brainpoolP384r1
+	28:  CurveLegacy,      //brainpoolP512r1
+	30:  CurveLegacy,      //x448
+	256: CurveLegacy,      //ffdhe2048
+	257: CurveLegacy,      //ffdhe3072
+	258: CurveLegacy,      //ffdhe4096
+	259: CurveLegacy,      //ffdhe6144
+	260: CurveLegacy,      //ffdhe8192
+}
+
+//GetCurveClass classifies a named group. Known groups other than the recommended and legacy ones, i.e. the binary field,
+//sub-256 bit, secp256k1 and arbitrary explicit curves, are discouraged. Unknown IDs are not classified
+func GetCurveClass(id uint16) CurveClass {
+	if class, present := curveClasses[id]; present {
+		return class
+	}
+	if _, present := LookupCurveName(id); present && id != 0 {
+		return CurveDiscouraged
+	}
+	return ""
+}
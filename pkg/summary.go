@@ -0,0 +1,47 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+)
+
+//CipherSummary describes how fragmented cipher support is
+type CipherSummary struct {
+	Ciphers         int     //distinct ciphers supported by any client
+	CoverNinety     int     //fewest ciphers, taken by descending support, estimated to cover 90% of clients; 0 if none do
+	Median          float64 //median support of a cipher
+	BelowOnePercent int     //ciphers supported by fewer than 1% of clients
+}
+
+//CipherSummary computes aggregate descriptors of the cipher entries. Coverage is estimated as in RecommendedCipherSuites
+func (t TLSStatistics) CipherSummary() (s CipherSummary) {
+	s.Ciphers = len(t.Ciphers)
+	if s.Ciphers == 0 {
+		return
+	}
+	percents := make([]float64, 0, s.Ciphers)
+	for _, e := range t.Ciphers {
+		percents = append(percents, e.Percent)
+		if e.Percent < 0.01 {
+			s.BelowOnePercent++
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(percents)))
+	for i := range percents {
+		if independentCoverage(percents[:i+1]) >= 0.9 {
+			s.CoverNinety = i + 1
+			break
+		}
+	}
+	if mid := len(percents) / 2; len(percents)%2 == 1 {
+		s.Median = percents[mid]
+	} else {
+		s.Median = (percents[mid-1] + percents[mid]) / 2
+	}
+	return
+}
+
+func (s CipherSummary) String() string {
+	return fmt.Sprintf("%d ciphers, %d cover 90%% of clients, median support %.2f%%, %d below 1%%",
+		s.Ciphers, s.CoverNinety, 100*s.Median, s.BelowOnePercent)
+}
@@ -0,0 +1,50 @@
+package stats
+
+import "fmt"
+
+//percentTolerance allows for rounding in percentages computed from counts
+const percentTolerance = 1e-9
+
+//Validate checks invariants of the statistics that bad data or a bug in the join would break: each percentage is a
+//fraction between 0 and 1, and the clients matched to the device profiles add up to the matched clients of the
+//Quality report, which the Total includes. The errors, one per violation, match ErrImplausibleStats
+func (t TLSStatistics) Validate() error {
+	errs := []error{}
+	for _, c := range t.categories() {
+		for _, e := range c.entries {
+			if e.Percent < 0 || e.Percent > 1+percentTolerance {
+				errs = append(errs, fmt.Errorf("%w: %s %s (%#04x) has a percent of %g", ErrImplausibleStats, c.name, e.Name, e.ID, e.Percent))
+			}
+		}
+	}
+	if t.LegacyOnly < 0 || t.LegacyOnly > 1+percentTolerance {
+		errs = append(errs, fmt.Errorf("%w: the legacy only fraction is %g", ErrImplausibleStats, t.LegacyOnly))
+	}
+	if t.Downgradable < 0 || t.Downgradable > 1+percentTolerance {
+		errs = append(errs, fmt.Errorf("%w: the downgradable fraction is %g", ErrImplausibleStats, t.Downgradable))
+	}
+	if len(t.DeviceCounts) > 0 && t.Quality.MatchedCount > 0 {
+		devices := int64(0)
+		for _, count := range t.DeviceCounts {
+			devices += count
+		}
+		if devices != t.Quality.MatchedCount {
+			errs = append(errs, fmt.Errorf("%w: the device counts add up to %d, not the %d matched clients", ErrImplausibleStats, devices, t.Quality.MatchedCount))
+		}
+		if t.Total < t.Quality.MatchedCount {
+			errs = append(errs, fmt.Errorf("%w: the total of %d is less than the %d matched clients", ErrImplausibleStats, t.Total, t.Quality.MatchedCount))
+		}
+	}
+	return joinErrors(errs)
+}
+
+//validate checks the statistics, see TLSStatistics.Validate. Implausible statistics are an error with the config's
+//StrictValidation, and otherwise only logged
+func (config Config) validate(statistics TLSStatistics) error {
+	err := statistics.Validate()
+	if err == nil || config.StrictValidation {
+		return err
+	}
+	logf("The statistics look implausible: %v", err)
+	return nil
+}